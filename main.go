@@ -1,66 +1,187 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"gokula/vm"
+	"io"
 	"os"
+	"strings"
 )
 
 type startupInfo struct {
 	method string
 	path   string
+	output string
+	allow  []string
+	args   []string
 }
 
 func main() {
 	startupInfo, err := readArgs()
 	if err != nil {
 		info()
+		os.Exit(1)
+	} else if startupInfo.method == "asm" {
+		err = assemble(startupInfo)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err.Error())
+			os.Exit(1)
+		}
 	} else {
-		var err error
-		vm.CompiledFileInstance, err = vm.Load(startupInfo.path)
+		compiledFile, err := vm.Load(startupInfo.path)
 		if err != nil {
 			fmt.Printf("Error: %s\n", err.Error())
-			return
+			os.Exit(1)
 		}
 		if startupInfo.method == "show" {
-			fmt.Println(vm.CompiledFileInstance)
+			fmt.Println(compiledFile)
+		} else if startupInfo.method == "disasm" {
+			err = disassemble(startupInfo, compiledFile)
+			if err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+		} else if startupInfo.method == "verify" {
+			errs := vm.Verify(compiledFile)
+			for _, e := range errs {
+				fmt.Println(e.Error())
+			}
+			if len(errs) > 0 {
+				os.Exit(1)
+			}
+			fmt.Println("ok")
 		} else if startupInfo.method == "run" {
-			err = vm.CompiledFileInstance.Run()
+			opts, err := machineOptions(startupInfo)
+			if err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+			err = vm.NewMachine(compiledFile, opts...).Run()
 			if err != nil {
 				fmt.Println("error: ", err)
+				var runtimeError *vm.RuntimeError
+				if errors.As(err, &runtimeError) {
+					fmt.Print(runtimeError.StackTrace())
+				}
+				os.Exit(1)
 			}
 		}
 	}
 }
 
+func machineOptions(si startupInfo) ([]vm.Option, error) {
+	opts := []vm.Option{vm.WithArgs(si.args...)}
+	if si.allow != nil {
+		caps := make([]vm.Capability, 0, len(si.allow))
+		for _, name := range si.allow {
+			c, err := vm.ParseCapability(name)
+			if err != nil {
+				return nil, err
+			}
+			caps = append(caps, c)
+		}
+		opts = append(opts, vm.WithCapabilities(caps...))
+	}
+	return opts, nil
+}
+
+func assemble(si startupInfo) error {
+	in, err := os.Open(si.path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	compiledFile, err := vm.Assemble(in)
+	if err != nil {
+		return err
+	}
+	output := si.output
+	if output == "" {
+		output = strings.TrimSuffix(si.path, ".kasm") + ".kulac"
+	}
+	out, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	_, err = compiledFile.WriteTo(out)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func disassemble(si startupInfo, compiledFile *vm.CompiledFile) error {
+	var out io.Writer = os.Stdout
+	if si.output != "" {
+		file, err := os.Create(si.output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	return vm.Disassemble(compiledFile, out)
+}
+
 func readArgs() (startupInfo, error) {
 	si := startupInfo{}
 	err := fmt.Errorf("illegal args")
-	if len(os.Args) < 2 {
+	if len(os.Args) < 3 {
 		return si, err
 	}
-	for index, str := range os.Args {
-		if index == 0 {
-			continue
-		} else if index == 1 {
+	for index := 1; index < len(os.Args); index++ {
+		str := os.Args[index]
+		if index == 1 {
 			if str == "-r" || str == "--run" {
 				si.method = "run"
 			} else if str == "-s" || str == "--show" {
 				si.method = "show"
+			} else if str == "asm" || str == "disasm" || str == "verify" {
+				si.method = str
 			} else {
 				return si, err
 			}
-		} else if index == 2 {
+		} else if str == "-o" {
+			if index+1 >= len(os.Args) {
+				return si, err
+			}
+			index++
+			si.output = os.Args[index]
+		} else if strings.HasPrefix(str, "--allow=") && si.path == "" {
+			si.allow = make([]string, 0)
+			for _, name := range strings.Split(strings.TrimPrefix(str, "--allow="), ",") {
+				if name != "" {
+					si.allow = append(si.allow, name)
+				}
+			}
+		} else if si.path == "" {
 			si.path = str
+		} else {
+			si.args = append(si.args, str)
 		}
 	}
+	if si.path == "" {
+		return si, err
+	}
 	return si, nil
 }
 
 func info() {
-	str := `Usage:	gokula <command> <*.kulac> [<args>]
+	str := `Usage:	gokula <command> [--allow=<capabilities>] <*.kulac> [<args>]
+	gokula asm <*.kasm> [-o <*.kulac>]
+	gokula disasm <*.kulac> [-o <*.kasm>]
+	gokula verify <*.kulac>
 
 	-r, --run	Run a kula-compiled-file in release mode
-	-s, --show	Output a kula-compiled-file in bytecode format`
+	-s, --show	Output a kula-compiled-file in bytecode format
+	asm		Assemble a kula-assembly-file into a kula-compiled-file
+	disasm		Disassemble a kula-compiled-file into kula-assembly
+	verify		Check a kula-compiled-file for malformed bytecode
+
+	--allow=time,io,os,env,random
+			Only grant the listed capabilities to the script,
+			which otherwise only gets time`
 	fmt.Println(str)
 }
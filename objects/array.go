@@ -1,11 +1,13 @@
 package objects
 
-import "strings"
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
 
 type KulaArray []any
 
-var ArrayProto *KulaObject = NewObject()
-
 func NewArray() *KulaArray {
 	a := make([]any, 0)
 	return (*KulaArray)(&a)
@@ -48,19 +50,202 @@ func (a *KulaArray) Length() KulaNumber {
 	return FromInt(len(*a))
 }
 
-func (a *KulaArray) String() string {
-	var sb strings.Builder
-	sb.Grow(64)
-	sb.WriteByte('[')
-	slice := make([]string, len(*a))
+func (a *KulaArray) Push(values ...any) {
+	(*a) = append((*a), values...)
+}
+
+func (a *KulaArray) Pop() any {
+	if len(*a) == 0 {
+		return nil
+	}
+	last := (*a)[len(*a)-1]
+	(*a) = (*a)[:len(*a)-1]
+	return last
+}
+
+// clampIndex resolves a possibly negative index against length n.
+func clampIndex(index KulaNumber, n int) int {
+	i := int(index)
+	if i < 0 {
+		i += n
+	}
+	return max(0, min(i, n))
+}
+
+// Slice copies the items from start up to end, where negative indices
+// count from the end.
+func (a *KulaArray) Slice(start, end KulaNumber) *KulaArray {
+	i, j := clampIndex(start, len(*a)), clampIndex(end, len(*a))
+	slice := make([]any, 0, max(0, j-i))
+	if i < j {
+		slice = append(slice, (*a)[i:j]...)
+	}
+	return FromSlice(slice)
+}
+
+func (a *KulaArray) Concat(others ...*KulaArray) *KulaArray {
+	slice := make([]any, 0, len(*a))
+	slice = append(slice, (*a)...)
+	for _, other := range others {
+		slice = append(slice, (*other)...)
+	}
+	return FromSlice(slice)
+}
+
+func (a *KulaArray) Join(seprator string) string {
+	strs := make([]string, len(*a))
+	for index, item := range *a {
+		strs[index] = string(*Stringify(item))
+	}
+	return strings.Join(strs, seprator)
+}
+
+func (a *KulaArray) IndexOf(value any) KulaNumber {
 	for index, item := range *a {
-		str := string(*Stringify(item))
-		if _, ok := item.(*KulaString); ok {
-			str = "\"" + str + "\""
+		if Equal(item, value) {
+			return FromInt(index)
+		}
+	}
+	return -1
+}
+
+func (a *KulaArray) Reverse() {
+	for i, j := 0, len(*a)-1; i < j; i, j = i+1, j-1 {
+		(*a)[i], (*a)[j] = (*a)[j], (*a)[i]
+	}
+}
+
+// ArrayCallback is called with every item and its index by the iterating
+// methods. Items appended during iteration are not visited.
+type ArrayCallback func(item any, index int) (any, error)
+
+func (a *KulaArray) each(fn ArrayCallback, visit func(item, result any) bool) error {
+	n := len(*a)
+	for i := 0; i < n && i < len(*a); i++ {
+		item := (*a)[i]
+		result, err := fn(item, i)
+		if err != nil {
+			return err
+		}
+		if !visit(item, result) {
+			break
+		}
+	}
+	return nil
+}
+
+func (a *KulaArray) ForEach(fn ArrayCallback) error {
+	return a.each(fn, func(item, result any) bool { return true })
+}
+
+func (a *KulaArray) Map(fn ArrayCallback) (*KulaArray, error) {
+	slice := make([]any, 0, len(*a))
+	err := a.each(fn, func(item, result any) bool {
+		slice = append(slice, result)
+		return true
+	})
+	return FromSlice(slice), err
+}
+
+func (a *KulaArray) Filter(fn ArrayCallback) (*KulaArray, error) {
+	slice := make([]any, 0)
+	err := a.each(fn, func(item, result any) bool {
+		if Booleanify(result) {
+			slice = append(slice, item)
+		}
+		return true
+	})
+	return FromSlice(slice), err
+}
+
+func (a *KulaArray) Find(fn ArrayCallback) (found any, err error) {
+	err = a.each(fn, func(item, result any) bool {
+		if Booleanify(result) {
+			found = item
+			return false
+		}
+		return true
+	})
+	return found, err
+}
+
+func (a *KulaArray) Some(fn ArrayCallback) (some KulaBool, err error) {
+	err = a.each(fn, func(item, result any) bool {
+		some = Booleanify(result)
+		return !bool(some)
+	})
+	return some, err
+}
+
+func (a *KulaArray) Every(fn ArrayCallback) (KulaBool, error) {
+	every := KulaBool(true)
+	err := a.each(fn, func(item, result any) bool {
+		every = Booleanify(result)
+		return bool(every)
+	})
+	return every, err
+}
+
+// Reduce folds the items into acc, which starts as the first item when
+// no initial value is given.
+func (a *KulaArray) Reduce(fn func(acc, item any, index int) (any, error), init ...any) (any, error) {
+	var acc any
+	start := 0
+	if len(init) > 0 {
+		acc = init[0]
+	} else if len(*a) > 0 {
+		acc = (*a)[0]
+		start = 1
+	} else {
+		return nil, fmt.Errorf("reduce of empty array with no initial value")
+	}
+	n := len(*a)
+	for i := start; i < n && i < len(*a); i++ {
+		var err error
+		acc, err = fn(acc, (*a)[i], i)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return acc, nil
+}
+
+// Sort stably sorts the array with less, stopping at the first error. The
+// items are sorted in a copy, so less may change the array meanwhile; the
+// sorted items replace its contents at the end.
+func (a *KulaArray) Sort(less func(x, y any) (bool, error)) error {
+	items := append([]any(nil), *a...)
+	var err error
+	sort.SliceStable(items, func(i, j int) bool {
+		if err != nil {
+			return false
+		}
+		var ok bool
+		ok, err = less(items[i], items[j])
+		return ok
+	})
+	if err != nil {
+		return err
+	}
+	*a = items
+	return nil
+}
+
+// DefaultLess orders numbers numerically and strings by code point.
+func DefaultLess(x, y any) (bool, error) {
+	if n1, ok := x.(KulaNumber); ok {
+		if n2, ok := y.(KulaNumber); ok {
+			return n1 < n2, nil
+		}
+	}
+	if s1, ok := x.(*KulaString); ok {
+		if s2, ok := y.(*KulaString); ok {
+			return *s1 < *s2, nil
 		}
-		slice[index] = str
 	}
-	sb.Write([]byte(strings.Join(slice, ",")))
-	sb.WriteByte(']')
-	return sb.String()
+	return false, fmt.Errorf("cannot compare '%s' and '%s'", *Stringify(x), *Stringify(y))
+}
+
+func (a *KulaArray) String() string {
+	return string(*Stringify(a))
 }
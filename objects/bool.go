@@ -2,8 +2,6 @@ package objects
 
 type KulaBool bool
 
-var BoolProto *KulaObject = NewObject()
-
 func Booleanify(v any) KulaBool {
 	if v == nil {
 		return false
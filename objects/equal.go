@@ -0,0 +1,86 @@
+package objects
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"math"
+	"reflect"
+)
+
+// Equal reports whether a and b are the same Kula value. Strings compare
+// by content and numbers numerically, so NaN is not equal to itself and
+// -0 equals 0. Arrays, objects and functions compare by identity.
+func Equal(a, b any) bool {
+	switch x := a.(type) {
+	case KulaNumber:
+		y, ok := b.(KulaNumber)
+		return ok && x == y
+	case *KulaString:
+		y, ok := b.(*KulaString)
+		return ok && (x == y || x != nil && y != nil && *x == *y)
+	}
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb {
+		return false
+	}
+	if ta != nil && !ta.Comparable() {
+		if !hasPointer(ta.Kind()) {
+			return false
+		}
+		return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+	}
+	return a == b
+}
+
+func hasPointer(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return true
+	}
+	return false
+}
+
+var hashSeed = maphash.MakeSeed()
+
+// Hash returns a hash of v consistent with Equal: values that are Equal
+// hash to the same number. Every NaN hashes alike, so that KulaMap can
+// treat them as a single key.
+func Hash(v any) uint64 {
+	var h maphash.Hash
+	h.SetSeed(hashSeed)
+	var buf [8]byte
+	switch x := v.(type) {
+	case nil:
+		h.WriteByte(0)
+	case KulaBool:
+		h.WriteByte(1)
+		if x {
+			h.WriteByte(1)
+		} else {
+			h.WriteByte(0)
+		}
+	case KulaNumber:
+		f := float64(x)
+		if f == 0 {
+			f = 0
+		} else if math.IsNaN(f) {
+			f = math.NaN()
+		}
+		h.WriteByte(2)
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(f))
+		h.Write(buf[:])
+	case *KulaString:
+		h.WriteByte(3)
+		if x != nil {
+			h.WriteString(string(*x))
+		}
+	default:
+		h.WriteByte(4)
+		h.WriteString(reflect.TypeOf(v).String())
+		if rv := reflect.ValueOf(v); hasPointer(rv.Kind()) {
+			binary.LittleEndian.PutUint64(buf[:], uint64(rv.Pointer()))
+			h.Write(buf[:])
+		}
+	}
+	return h.Sum64()
+}
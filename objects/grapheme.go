@@ -0,0 +1,74 @@
+package objects
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Graphemes splits s into user-perceived characters. It approximates the
+// extended grapheme clusters of UAX #29: CR LF, combining marks, variation
+// selectors, emoji modifiers and tags, ZWJ sequences and regional
+// indicator pairs are kept together.
+func (s *KulaString) Graphemes() *KulaArray {
+	clusters := make([]any, 0)
+	str := string(*s)
+	for len(str) > 0 {
+		size := graphemeSize(str)
+		cluster := KulaString(str[:size])
+		clusters = append(clusters, &cluster)
+		str = str[size:]
+	}
+	return FromSlice(clusters)
+}
+
+func (s *KulaString) GraphemeLength() KulaNumber {
+	n := 0
+	for str := string(*s); len(str) > 0; n++ {
+		str = str[graphemeSize(str):]
+	}
+	return FromInt(n)
+}
+
+// graphemeSize returns the byte size of the cluster starting str.
+func graphemeSize(str string) int {
+	first, size := utf8.DecodeRuneInString(str)
+	if first == '\r' && len(str) > size && str[size] == '\n' {
+		return size + 1
+	}
+	if first == '\r' || first == '\n' {
+		return size
+	}
+	prev := first
+	regional := isRegionalIndicator(first)
+	for size < len(str) {
+		r, n := utf8.DecodeRuneInString(str[size:])
+		switch {
+		case isExtend(r):
+		case prev == '\u200d' && !isExtend(r) && r != '\r' && r != '\n':
+			// joined by ZWJ
+		case regional && isRegionalIndicator(r):
+			regional = false
+		default:
+			return size
+		}
+		if !isRegionalIndicator(r) {
+			regional = false
+		}
+		prev = r
+		size += n
+	}
+	return size
+}
+
+func isExtend(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+		r == '\u200d' ||
+		(r >= 0xfe00 && r <= 0xfe0f) ||
+		(r >= 0xe0100 && r <= 0xe01ef) ||
+		(r >= 0x1f3fb && r <= 0x1f3ff) ||
+		(r >= 0xe0020 && r <= 0xe007f)
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
@@ -0,0 +1,299 @@
+package objects
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxJSONDepth bounds the nesting of values read by FromJSON.
+const maxJSONDepth = 512
+
+// ToJSON encodes v as JSON. Nested values are placed on their own lines
+// prefixed by indent when it is not empty. Functions, unknown values and
+// cycles are reported as errors; __proto__ is never written.
+func ToJSON(v any, indent string) (string, error) {
+	e := &jsonEncoder{indent: indent, visiting: make(map[any]bool)}
+	err := e.encode(v, 0)
+	if err != nil {
+		return "", err
+	}
+	return e.sb.String(), nil
+}
+
+type jsonEncoder struct {
+	sb       strings.Builder
+	indent   string
+	visiting map[any]bool
+}
+
+func (e *jsonEncoder) newline(depth int) {
+	if e.indent == "" {
+		return
+	}
+	e.sb.WriteByte('\n')
+	for i := 0; i < depth; i++ {
+		e.sb.WriteString(e.indent)
+	}
+}
+
+func (e *jsonEncoder) enter(v any) error {
+	if e.visiting[v] {
+		return fmt.Errorf("cannot encode cyclic value as JSON")
+	}
+	e.visiting[v] = true
+	return nil
+}
+
+func (e *jsonEncoder) encode(v any, depth int) error {
+	switch val := v.(type) {
+	case nil:
+		e.sb.WriteString("null")
+	case KulaBool:
+		e.sb.WriteString(strconv.FormatBool(bool(val)))
+	case KulaNumber:
+		e.sb.WriteString(jsonNumber(float64(val)))
+	case *KulaString:
+		e.sb.WriteString(QuoteJSON(string(*val)))
+	case *KulaArray:
+		err := e.enter(val)
+		if err != nil {
+			return err
+		}
+		defer delete(e.visiting, val)
+		e.sb.WriteByte('[')
+		for i, item := range *val {
+			if i > 0 {
+				e.sb.WriteByte(',')
+			}
+			e.newline(depth + 1)
+			err := e.encode(item, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+		if len(*val) > 0 {
+			e.newline(depth)
+		}
+		e.sb.WriteByte(']')
+	case *KulaObject:
+		err := e.enter(val)
+		if err != nil {
+			return err
+		}
+		defer delete(e.visiting, val)
+		keys := make([]string, 0, val.Len())
+		for _, key := range val.keys {
+			if key != PROTO__ {
+				keys = append(keys, key)
+			}
+		}
+		e.sb.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				e.sb.WriteByte(',')
+			}
+			e.newline(depth + 1)
+			e.sb.WriteString(QuoteJSON(key))
+			e.sb.WriteByte(':')
+			if e.indent != "" {
+				e.sb.WriteByte(' ')
+			}
+			err := e.encode(val.values[key], depth+1)
+			if err != nil {
+				return err
+			}
+		}
+		if len(keys) > 0 {
+			e.newline(depth)
+		}
+		e.sb.WriteByte('}')
+	case *KulaMap:
+		return fmt.Errorf("cannot encode Map as JSON")
+	case *KulaSet:
+		return fmt.Errorf("cannot encode Set as JSON")
+	default:
+		if s, ok := v.(fmt.Stringer); ok {
+			return fmt.Errorf("cannot encode %s as JSON", s.String())
+		}
+		return fmt.Errorf("cannot encode %T as JSON", v)
+	}
+	return nil
+}
+
+// jsonNumber formats f the way JavaScript does, writing null for NaN and
+// the infinities which JSON cannot represent.
+func jsonNumber(f float64) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "null"
+	}
+	abs := math.Abs(f)
+	if abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		return strconv.FormatFloat(f, 'e', -1, 64)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// QuoteJSON returns s as a JSON string literal. Invalid UTF-8 is replaced
+// with U+FFFD.
+func QuoteJSON(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s) + 2)
+	sb.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		switch {
+		case r == '"':
+			sb.WriteString(`\"`)
+		case r == '\\':
+			sb.WriteString(`\\`)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r == '\b':
+			sb.WriteString(`\b`)
+		case r == '\f':
+			sb.WriteString(`\f`)
+		case r < 0x20 || r == '\u2028' || r == '\u2029':
+			fmt.Fprintf(&sb, `\u%04x`, r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// FromJSON decodes text into Kula values: objects become *KulaObject, arrays
+// *KulaArray, numbers KulaNumber, strings *KulaString, booleans KulaBool and
+// null nil. Objects with a __proto__ key are rejected, since the key would
+// set their prototype.
+func FromJSON(text string) (any, error) {
+	return FromJSONWith(text, JSONOptions{})
+}
+
+// JSONOptions lets the caller account for the values FromJSONWith builds.
+// AddElement is called before each array element is appended and AddKey
+// before each new object key is set. An error from either stops decoding
+// and is returned unchanged.
+type JSONOptions struct {
+	AddElement func() error
+	AddKey     func() error
+}
+
+// FromJSONWith decodes text like FromJSON, reporting containers to opts.
+func FromJSONWith(text string, opts JSONOptions) (any, error) {
+	d := &jsonDecoder{dec: json.NewDecoder(strings.NewReader(text)), opts: opts}
+	d.dec.UseNumber()
+	v, err := d.decode(0)
+	if d.err != nil {
+		return nil, d.err
+	}
+	if err != nil {
+		return nil, jsonError(err)
+	}
+	if _, err := d.dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid JSON: unexpected data after top-level value at offset %d", d.dec.InputOffset())
+	}
+	return v, nil
+}
+
+func jsonError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("invalid JSON: unexpected end of input")
+	}
+	return fmt.Errorf("invalid JSON: %w", err)
+}
+
+type jsonDecoder struct {
+	dec  *json.Decoder
+	opts JSONOptions
+	// err holds the failure of an opts callback, which is not a JSON error.
+	err error
+}
+
+// add reports a new container item to fn.
+func (d *jsonDecoder) add(fn func() error) error {
+	if fn == nil {
+		return nil
+	}
+	d.err = fn()
+	return d.err
+}
+
+func (d *jsonDecoder) decode(depth int) (any, error) {
+	if depth > maxJSONDepth {
+		return nil, fmt.Errorf("nesting deeper than %d", maxJSONDepth)
+	}
+	token, err := d.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok := token.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		return KulaBool(tok), nil
+	case json.Number:
+		f, err := strconv.ParseFloat(string(tok), 64)
+		if err != nil {
+			return nil, fmt.Errorf("number %s out of range", tok)
+		}
+		return KulaNumber(f), nil
+	case string:
+		str := KulaString(tok)
+		return &str, nil
+	case json.Delim:
+		if tok == '[' {
+			arr := NewArray()
+			for d.dec.More() {
+				item, err := d.decode(depth + 1)
+				if err != nil {
+					return nil, err
+				}
+				err = d.add(d.opts.AddElement)
+				if err != nil {
+					return nil, err
+				}
+				*arr = append(*arr, item)
+			}
+			_, err := d.dec.Token()
+			return arr, err
+		}
+		obj := NewObject()
+		for d.dec.More() {
+			token, err := d.dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := token.(string)
+			if key == PROTO__ {
+				// the key would become a prototype link instead of data
+				return nil, fmt.Errorf("key %q is not allowed", PROTO__)
+			}
+			value, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := obj.Own(key); !ok {
+				err = d.add(d.opts.AddKey)
+				if err != nil {
+					return nil, err
+				}
+			}
+			obj.SetNative(key, value)
+		}
+		_, err := d.dec.Token()
+		return obj, err
+	}
+	return nil, fmt.Errorf("unexpected token %v", token)
+}
@@ -0,0 +1,64 @@
+package objects
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	for _, text := range []string{
+		`null`,
+		`[1,2.5,-3e-7,"x\n \"",true,false,null]`,
+		`{"z":1,"a":{"nested":[{}]},"m":"é"}`,
+		`{"proto":{"__proto":1}}`,
+	} {
+		v, err := FromJSON(text)
+		if err != nil {
+			t.Fatalf("parse %s: %v", text, err)
+		}
+		out, err := ToJSON(v, "")
+		if err != nil {
+			t.Fatalf("stringify %s: %v", text, err)
+		}
+		again, err := FromJSON(out)
+		if err != nil {
+			t.Fatalf("reparse %s: %v", out, err)
+		}
+		if out2, _ := ToJSON(again, ""); out2 != out {
+			t.Errorf("round trip of %s changed %s to %s", text, out, out2)
+		}
+	}
+}
+
+func TestJSONRejectsProto(t *testing.T) {
+	for _, text := range []string{
+		`{"__proto__":{"admin":true}}`,
+		`[{"a":1,"__proto__":null}]`,
+	} {
+		v, err := FromJSON(text)
+		if err == nil || !strings.Contains(err.Error(), PROTO__) {
+			t.Errorf("parse %s = %v, %v; want __proto__ error", text, v, err)
+		}
+	}
+}
+
+func TestJSONCountsItems(t *testing.T) {
+	var elements, keys int
+	_, err := FromJSONWith(`{"a":[1,2,[3]],"b":{"c":null},"a":[]}`, JSONOptions{
+		AddElement: func() error { elements++; return nil },
+		AddKey:     func() error { keys++; return nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elements != 4 || keys != 3 {
+		t.Errorf("counted %d elements and %d keys, want 4 and 3", elements, keys)
+	}
+
+	limit := errors.New("limit")
+	_, err = FromJSONWith(`[1,2,3]`, JSONOptions{AddElement: func() error { return limit }})
+	if err != limit {
+		t.Errorf("error = %v, want the callback error unchanged", err)
+	}
+}
@@ -0,0 +1,217 @@
+package objects
+
+import "math"
+
+type mapEntry struct {
+	key, value any
+	deleted    bool
+}
+
+// KulaMap maps arbitrary values to values, comparing keys with Equal
+// except that NaN is treated as equal to itself. Entries are iterated in
+// insertion order.
+type KulaMap struct {
+	entries   []mapEntry
+	buckets   map[uint64][]int
+	size      int
+	iterating int
+}
+
+func NewMap() *KulaMap {
+	return &KulaMap{buckets: make(map[uint64][]int)}
+}
+
+func sameKey(a, b any) bool {
+	if Equal(a, b) {
+		return true
+	}
+	x, ok1 := a.(KulaNumber)
+	y, ok2 := b.(KulaNumber)
+	return ok1 && ok2 && math.IsNaN(float64(x)) && math.IsNaN(float64(y))
+}
+
+func (m *KulaMap) find(key any) int {
+	for _, i := range m.buckets[Hash(key)] {
+		if sameKey(m.entries[i].key, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *KulaMap) Get(key any) any {
+	if i := m.find(key); i >= 0 {
+		return m.entries[i].value
+	}
+	return nil
+}
+
+func (m *KulaMap) Has(key any) bool {
+	return m.find(key) >= 0
+}
+
+// Set stores value under key and reports whether key was new.
+func (m *KulaMap) Set(key, value any) bool {
+	if i := m.find(key); i >= 0 {
+		m.entries[i].value = value
+		return false
+	}
+	if m.buckets == nil {
+		m.buckets = make(map[uint64][]int)
+	}
+	h := Hash(key)
+	m.buckets[h] = append(m.buckets[h], len(m.entries))
+	m.entries = append(m.entries, mapEntry{key: key, value: value})
+	m.size++
+	return true
+}
+
+// Delete removes key and reports whether it was present.
+func (m *KulaMap) Delete(key any) bool {
+	i := m.find(key)
+	if i < 0 {
+		return false
+	}
+	h := Hash(key)
+	bucket := m.buckets[h]
+	for j, index := range bucket {
+		if index == i {
+			bucket = append(bucket[:j], bucket[j+1:]...)
+			break
+		}
+	}
+	if len(bucket) == 0 {
+		delete(m.buckets, h)
+	} else {
+		m.buckets[h] = bucket
+	}
+	m.entries[i] = mapEntry{deleted: true}
+	m.size--
+	m.compact()
+	return true
+}
+
+// compact drops deleted entries once they outnumber the live ones, unless
+// an iteration is relying on the entry indices.
+func (m *KulaMap) compact() {
+	if m.iterating > 0 || len(m.entries) < 2*m.size+8 {
+		return
+	}
+	entries := make([]mapEntry, 0, m.size)
+	buckets := make(map[uint64][]int, m.size)
+	for _, entry := range m.entries {
+		if entry.deleted {
+			continue
+		}
+		h := Hash(entry.key)
+		buckets[h] = append(buckets[h], len(entries))
+		entries = append(entries, entry)
+	}
+	m.entries, m.buckets = entries, buckets
+}
+
+func (m *KulaMap) Size() KulaNumber {
+	return FromInt(m.size)
+}
+
+// Each calls fn with every entry in insertion order, stopping at the first
+// error. Entries added during the iteration are visited as well.
+func (m *KulaMap) Each(fn func(key, value any) error) error {
+	m.iterating++
+	defer func() {
+		m.iterating--
+		m.compact()
+	}()
+	for i := 0; i < len(m.entries); i++ {
+		entry := m.entries[i]
+		if entry.deleted {
+			continue
+		}
+		err := fn(entry.key, entry.value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *KulaMap) Keys() *KulaArray {
+	slice := make([]any, 0, m.size)
+	for _, entry := range m.entries {
+		if !entry.deleted {
+			slice = append(slice, entry.key)
+		}
+	}
+	return FromSlice(slice)
+}
+
+func (m *KulaMap) Values() *KulaArray {
+	slice := make([]any, 0, m.size)
+	for _, entry := range m.entries {
+		if !entry.deleted {
+			slice = append(slice, entry.value)
+		}
+	}
+	return FromSlice(slice)
+}
+
+// Entries returns the entries as [key, value] arrays.
+func (m *KulaMap) Entries() *KulaArray {
+	slice := make([]any, 0, m.size)
+	for _, entry := range m.entries {
+		if !entry.deleted {
+			slice = append(slice, FromSlice([]any{entry.key, entry.value}))
+		}
+	}
+	return FromSlice(slice)
+}
+
+func (m *KulaMap) String() string {
+	return string(*Stringify(m))
+}
+
+// KulaSet is a collection of distinct values, compared like KulaMap keys
+// and iterated in insertion order.
+type KulaSet struct {
+	items KulaMap
+}
+
+func NewSet() *KulaSet {
+	return &KulaSet{items: *NewMap()}
+}
+
+// Add inserts value and reports whether it was new.
+func (s *KulaSet) Add(value any) bool {
+	return s.items.Set(value, value)
+}
+
+func (s *KulaSet) Has(value any) bool {
+	return s.items.Has(value)
+}
+
+func (s *KulaSet) Delete(value any) bool {
+	return s.items.Delete(value)
+}
+
+func (s *KulaSet) Size() KulaNumber {
+	return s.items.Size()
+}
+
+func (s *KulaSet) Each(fn func(value any) error) error {
+	return s.items.Each(func(key, value any) error {
+		return fn(key)
+	})
+}
+
+func (s *KulaSet) Values() *KulaArray {
+	return s.items.Keys()
+}
+
+// Entries returns the items as [value, value] arrays.
+func (s *KulaSet) Entries() *KulaArray {
+	return s.items.Entries()
+}
+
+func (s *KulaSet) String() string {
+	return string(*Stringify(s))
+}
@@ -0,0 +1,48 @@
+package objects
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMapNaNKeys(t *testing.T) {
+	quiet := KulaNumber(math.NaN())
+	var zero float64
+	division := KulaNumber(zero / zero)
+	if math.Float64bits(float64(quiet)) == math.Float64bits(float64(division)) {
+		t.Skip("platform produces identical NaN bits")
+	}
+	m := NewMap()
+	m.Set(quiet, KulaNumber(1))
+	if !m.Has(division) {
+		t.Errorf("NaN with other bits is not found")
+	}
+	if m.Set(division, KulaNumber(2)); m.Size() != 1 {
+		t.Errorf("size = %v, want 1", m.Size())
+	}
+}
+
+func TestMapKeys(t *testing.T) {
+	a, b := KulaString("k"), KulaString("k")
+	arr := NewArray()
+	m := NewMap()
+	m.Set(&a, KulaNumber(1))
+	m.Set(KulaNumber(0), KulaNumber(2))
+	m.Set(arr, KulaNumber(3))
+	if got := m.Get(&b); got != KulaNumber(1) {
+		t.Errorf("string key by value = %v, want 1", got)
+	}
+	if got := m.Get(KulaNumber(math.Copysign(0, -1))); got != KulaNumber(2) {
+		t.Errorf("-0 key = %v, want 2", got)
+	}
+	if m.Has(NewArray()) {
+		t.Errorf("arrays must be keyed by identity")
+	}
+	for i := 0; i < 50; i++ {
+		m.Set(FromInt(i+1), nil)
+		m.Delete(FromInt(i + 1))
+	}
+	if got := string(*Stringify(m)); got != `Map{"k"=>1,0=>2,[]=>3}` {
+		t.Errorf("after deletes = %s", got)
+	}
+}
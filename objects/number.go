@@ -1,11 +1,14 @@
 package objects
 
-import "math"
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
 
 type KulaNumber float64
 
-var NumberProto *KulaObject = NewObject()
-
 func (n KulaNumber) Floor() KulaNumber {
 	return FromFloat64(math.Floor(float64(n)))
 }
@@ -21,3 +24,50 @@ func FromInt(i int) KulaNumber {
 func FromFloat64(f float64) KulaNumber {
 	return KulaNumber(f)
 }
+
+// ToFixed formats n with exactly digits decimals.
+func (n KulaNumber) ToFixed(digits int) (string, error) {
+	if digits < 0 || digits > 100 {
+		return "", fmt.Errorf("toFixed digits must be between 0 and 100")
+	}
+	return strconv.FormatFloat(float64(n), 'f', digits, 64), nil
+}
+
+// ToString formats n in radix, writing at most 52 fractional digits.
+func (n KulaNumber) ToString(radix int) (string, error) {
+	if radix < 2 || radix > 36 {
+		return "", fmt.Errorf("radix must be between 2 and 36")
+	}
+	f := float64(n)
+	if radix == 10 || math.IsNaN(f) || math.IsInf(f, 0) {
+		return string(*Stringify(n)), nil
+	}
+
+	var sb strings.Builder
+	if f < 0 {
+		sb.WriteByte('-')
+		f = -f
+	}
+	integer, fraction := math.Modf(f)
+	if integer < 1<<63 {
+		sb.WriteString(strconv.FormatUint(uint64(integer), radix))
+	} else {
+		digits := make([]byte, 0)
+		for ; integer >= 1; integer = math.Floor(integer / float64(radix)) {
+			digits = append(digits, strconv.FormatInt(int64(math.Mod(integer, float64(radix))), radix)[0])
+		}
+		for i := len(digits) - 1; i >= 0; i-- {
+			sb.WriteByte(digits[i])
+		}
+	}
+	if fraction > 0 {
+		sb.WriteByte('.')
+		for i := 0; i < 52 && fraction > 0; i++ {
+			fraction *= float64(radix)
+			digit, rest := math.Modf(fraction)
+			sb.WriteString(strconv.FormatInt(int64(digit), radix))
+			fraction = rest
+		}
+	}
+	return sb.String(), nil
+}
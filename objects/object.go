@@ -1,60 +1,64 @@
 package objects
 
-import "strings"
+// KulaObject maps string keys to values and remembers the order in which
+// keys were first set, which is the order they are iterated and printed in.
+type KulaObject struct {
+	keys   []string
+	values map[string]any
+}
 
-type KulaObject map[string]any
+// maxProtoDepth bounds __proto__ chains so that a cyclic chain cannot hang a lookup.
+const maxProtoDepth = 256
 
-var ObjectProto = newObject()
+func NewObject() *KulaObject {
+	return &KulaObject{values: make(map[string]any)}
+}
 
-func newObject() *KulaObject {
-	data := make(map[string]any)
-	return (*KulaObject)(&data)
+// Own returns the value stored under key on obj itself, ignoring __proto__.
+func (obj *KulaObject) Own(key string) (any, bool) {
+	val, ok := obj.values[key]
+	return val, ok
 }
 
-func NewObject() *KulaObject {
-	obj := newObject()
-	obj.SetNative(PROTO__, ObjectProto)
-	return obj
+// Keys returns the keys of obj in insertion order, including __proto__.
+func (obj *KulaObject) Keys() []string {
+	return append([]string(nil), obj.keys...)
 }
 
-func (obj *KulaObject) Get(key *KulaString) any {
-	val, ok := (*obj)[string(*key)]
-	if ok {
-		return val
-	}
-	val, ok = (*obj)[PROTO__]
-	if !ok {
-		return nil
-	}
-	proto, ok := val.(*KulaObject)
-	if ok {
-		return proto.Get(key)
+func (obj *KulaObject) Len() int {
+	return len(obj.keys)
+}
+
+// Lookup resolves key through the __proto__ chain and reports whether it was found.
+func (obj *KulaObject) Lookup(key string) (any, bool) {
+	for depth := 0; obj != nil && depth < maxProtoDepth; depth++ {
+		if val, ok := obj.values[key]; ok {
+			return val, true
+		}
+		obj, _ = obj.values[PROTO__].(*KulaObject)
 	}
-	return nil
+	return nil, false
+}
+
+func (obj *KulaObject) Get(key *KulaString) any {
+	val, _ := obj.Lookup(string(*key))
+	return val
 }
 
 func (obj *KulaObject) Set(key *KulaString, value any) {
-	(*obj)[string(*key)] = value
+	obj.SetNative(string(*key), value)
 }
 
 func (obj *KulaObject) SetNative(key string, value any) {
-	(*obj)[key] = value
+	if obj.values == nil {
+		obj.values = make(map[string]any)
+	}
+	if _, ok := obj.values[key]; !ok {
+		obj.keys = append(obj.keys, key)
+	}
+	obj.values[key] = value
 }
 
 func (obj *KulaObject) String() string {
-	var sb strings.Builder
-	sb.Grow(64)
-	sb.WriteByte('{')
-	slice := make([]string, 0)
-	for key, value := range *obj {
-		str := string(*Stringify(value))
-		if _, ok := value.(*KulaString); ok {
-			str = "\"" + str + "\""
-		}
-		str = "\"" + key + "\":" + str
-		slice = append(slice, str)
-	}
-	sb.Write([]byte(strings.Join(slice, ",")))
-	sb.WriteByte('}')
-	return sb.String()
+	return string(*Stringify(obj))
 }
@@ -1,55 +1,51 @@
 package objects
 
 import (
+	"math"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type KulaString string
 
-var StringProto *KulaObject = NewObject()
-
-func Stringify(v any) *KulaString {
-	var str KulaString
-	if v == nil {
-		str = "null"
-		return &str
-	} else if b, ok := v.(KulaBool); ok {
-		str = KulaString(strconv.FormatBool(bool(b)))
-		return &str
-	} else if number, ok := v.(KulaNumber); ok {
-		s1 := strconv.FormatFloat(float64(number), 'f', 8, 64)
-		s1 = strings.TrimSuffix(strings.TrimRight(s1, "0"), ".")
-		str = KulaString(s1)
-		return &str
-	} else if s, ok := v.(*KulaString); ok {
-		return s
-	} else if arr, ok := v.(*KulaArray); ok {
-		str = KulaString(arr.String())
-		return &str
-	} else if obj, ok := v.(*KulaObject); ok {
-		str = KulaString(obj.String())
-		return &str
+// Strings are indexed by code point. Fractional indices are truncated,
+// and an index out of range yields null instead of failing.
+
+// runeAt converts index into a code point index of runes, or reports false.
+func runeAt(index KulaNumber, runes []rune) (int, bool) {
+	if math.IsNaN(float64(index)) || index < 0 || index >= KulaNumber(len(runes)) {
+		return 0, false
 	}
-	str = "<UnknownValue>"
-	return &str
+	return int(index), true
 }
 
-func (s *KulaString) At(index KulaNumber) *KulaString {
-	i := int(index)
-	str := (string)(*s)
-	runes := []rune(str)
-	r := string(runes[i])
-	return (*KulaString)(&r)
+// At returns the code point at index as a string, or nil when out of range.
+func (s *KulaString) At(index KulaNumber) any {
+	runes := []rune(string(*s))
+	i, ok := runeAt(index, runes)
+	if !ok {
+		return nil
+	}
+	r := KulaString(runes[i])
+	return &r
 }
 
-func (s *KulaString) Cut(index, length KulaNumber) *KulaString {
+// Cut returns up to length code points from index, or nil when index is
+// out of range or length is negative. Index may equal the length.
+func (s *KulaString) Cut(index, length KulaNumber) any {
+	runes := []rune(string(*s))
+	if math.IsNaN(float64(index)) || index < 0 || index > KulaNumber(len(runes)) || !(length >= 0) {
+		return nil
+	}
 	i := int(index)
-	l := int(length)
-	str := (string)(*s)
-	runes := []rune(str)
-	r := string(runes[i : i+l])
-	return (*KulaString)(&r)
+	j := len(runes)
+	if length < KulaNumber(j-i) {
+		j = i + int(length)
+	}
+	r := KulaString(runes[i:j])
+	return &r
 }
 
 func (s *KulaString) Parse() (n KulaNumber) {
@@ -64,17 +60,150 @@ func (s *KulaString) Split(seprator *KulaString) *KulaArray {
 	strArr := strings.Split(string(*s), string(*seprator))
 	arr := make([]any, len(strArr))
 	for index, item := range strArr {
-		arr[index] = item
+		str := KulaString(item)
+		arr[index] = &str
 	}
 	return FromSlice(arr)
 }
 
+// Length counts code points, the unit every index of a KulaString uses.
 func (s *KulaString) Length() KulaNumber {
-	return FromInt(len(string(*s)))
+	return FromInt(utf8.RuneCountInString(string(*s)))
 }
 
-func (s *KulaString) CharCode(index KulaNumber) KulaNumber {
+// CharCode returns the code point at index, or nil when out of range.
+func (s *KulaString) CharCode(index KulaNumber) any {
 	runes := []rune(string(*s))
-	r := runes[int(index)]
-	return FromInt(int(r))
+	i, ok := runeAt(index, runes)
+	if !ok {
+		return nil
+	}
+	return FromInt(int(runes[i]))
+}
+
+// runeIndex converts a byte offset into s to a code point index.
+func (s *KulaString) runeIndex(offset int) KulaNumber {
+	if offset < 0 {
+		return -1
+	}
+	return FromInt(utf8.RuneCountInString(string(*s)[:offset]))
+}
+
+func (s *KulaString) IndexOf(sub *KulaString) KulaNumber {
+	return s.runeIndex(strings.Index(string(*s), string(*sub)))
+}
+
+func (s *KulaString) LastIndexOf(sub *KulaString) KulaNumber {
+	return s.runeIndex(strings.LastIndex(string(*s), string(*sub)))
+}
+
+func (s *KulaString) Contains(sub *KulaString) KulaBool {
+	return KulaBool(strings.Contains(string(*s), string(*sub)))
+}
+
+func (s *KulaString) StartsWith(prefix *KulaString) KulaBool {
+	return KulaBool(strings.HasPrefix(string(*s), string(*prefix)))
+}
+
+func (s *KulaString) EndsWith(suffix *KulaString) KulaBool {
+	return KulaBool(strings.HasSuffix(string(*s), string(*suffix)))
+}
+
+func (s *KulaString) Replace(old, new *KulaString) *KulaString {
+	r := KulaString(strings.Replace(string(*s), string(*old), string(*new), 1))
+	return &r
+}
+
+func (s *KulaString) ReplaceAll(old, new *KulaString) *KulaString {
+	r := KulaString(strings.ReplaceAll(string(*s), string(*old), string(*new)))
+	return &r
+}
+
+func (s *KulaString) Upper() *KulaString {
+	r := KulaString(strings.ToUpper(string(*s)))
+	return &r
+}
+
+func (s *KulaString) Lower() *KulaString {
+	r := KulaString(strings.ToLower(string(*s)))
+	return &r
+}
+
+func (s *KulaString) Trim() *KulaString {
+	r := KulaString(strings.TrimSpace(string(*s)))
+	return &r
+}
+
+func (s *KulaString) TrimStart() *KulaString {
+	r := KulaString(strings.TrimLeftFunc(string(*s), unicode.IsSpace))
+	return &r
+}
+
+func (s *KulaString) TrimEnd() *KulaString {
+	r := KulaString(strings.TrimRightFunc(string(*s), unicode.IsSpace))
+	return &r
+}
+
+func (s *KulaString) Repeat(count int) *KulaString {
+	r := KulaString(strings.Repeat(string(*s), count))
+	return &r
+}
+
+// padding repeats pad up to width code points, cutting the last repetition.
+func padding(width int, pad *KulaString) string {
+	str := string(*pad)
+	n := utf8.RuneCountInString(str)
+	if width <= 0 || n == 0 {
+		return ""
+	}
+	cut := 0
+	for i := 0; i < width%n; i++ {
+		_, size := utf8.DecodeRuneInString(str[cut:])
+		cut += size
+	}
+	var sb strings.Builder
+	sb.Grow(width/n*len(str) + cut)
+	for i := 0; i < width/n; i++ {
+		sb.WriteString(str)
+	}
+	sb.WriteString(str[:cut])
+	return sb.String()
+}
+
+func (s *KulaString) PadStart(length int, pad *KulaString) *KulaString {
+	r := KulaString(padding(length-int(s.Length()), pad)) + *s
+	return &r
+}
+
+func (s *KulaString) PadEnd(length int, pad *KulaString) *KulaString {
+	r := *s + KulaString(padding(length-int(s.Length()), pad))
+	return &r
+}
+
+func (s *KulaString) Chars() *KulaArray {
+	runes := []rune(string(*s))
+	arr := make([]any, len(runes))
+	for index, r := range runes {
+		str := KulaString(r)
+		arr[index] = &str
+	}
+	return FromSlice(arr)
+}
+
+func (s *KulaString) CodePoints() *KulaArray {
+	runes := []rune(string(*s))
+	arr := make([]any, len(runes))
+	for index, r := range runes {
+		arr[index] = FromInt(int(r))
+	}
+	return FromSlice(arr)
+}
+
+func FromCharCodes(codes ...KulaNumber) *KulaString {
+	runes := make([]rune, len(codes))
+	for index, code := range codes {
+		runes[index] = rune(code)
+	}
+	r := KulaString(runes)
+	return &r
 }
@@ -0,0 +1,94 @@
+package objects
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+const (
+	cjk    = "世界你好"
+	emoji  = "a😀b"
+	family = "\U0001F468\u200d\U0001F469\u200d\U0001F467"
+	flags  = "🇨🇳🇯🇵"
+)
+
+// render distinguishes null from strings and numbers in failure messages.
+func render(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case *KulaString:
+		return strconv.Quote(string(*val))
+	}
+	return string(*Stringify(v))
+}
+
+func TestStringConformance(t *testing.T) {
+	s := func(text string) *KulaString { return (*KulaString)(&text) }
+	nan := KulaNumber(math.NaN())
+
+	tests := []struct {
+		name string
+		got  any
+		want string
+	}{
+		{"cjk length", s(cjk).Length(), "4"},
+		{"cjk at", s(cjk).At(1), `"界"`},
+		{"cjk at last", s(cjk).At(3), `"好"`},
+		{"cjk at out of range", s(cjk).At(4), "null"},
+		{"cjk at negative", s(cjk).At(-1), "null"},
+		{"cjk at NaN", s(cjk).At(nan), "null"},
+		{"cjk at fraction", s(cjk).At(1.7), `"界"`},
+		{"cjk cut", s(cjk).Cut(1, 2), `"界你"`},
+		{"cjk cut past end", s(cjk).Cut(2, 10), `"你好"`},
+		{"cjk cut at end", s(cjk).Cut(4, 1), `""`},
+		{"cjk cut out of range", s(cjk).Cut(5, 1), "null"},
+		{"cjk cut NaN index", s(cjk).Cut(nan, 1), "null"},
+		{"cjk cut NaN length", s(cjk).Cut(0, nan), "null"},
+		{"cjk cut negative length", s(cjk).Cut(0, -1), "null"},
+		{"cjk charCode", s(cjk).CharCode(0), "19990"},
+		{"cjk charCode out of range", s(cjk).CharCode(4), "null"},
+		{"cjk charCode NaN", s(cjk).CharCode(nan), "null"},
+		{"cjk indexOf", s(cjk).IndexOf(s("你好")), "2"},
+		{"cjk lastIndexOf", s(cjk + cjk).LastIndexOf(s("界")), "5"},
+		{"cjk indexOf missing", s(cjk).IndexOf(s("a")), "-1"},
+
+		{"emoji length", s(emoji).Length(), "3"},
+		{"emoji at", s(emoji).At(1), `"😀"`},
+		{"emoji at after", s(emoji).At(2), `"b"`},
+		{"emoji at out of range", s(emoji).At(3), "null"},
+		{"emoji cut", s(emoji).Cut(1, 5), `"😀b"`},
+		{"emoji charCode", s(emoji).CharCode(1), "128512"},
+		{"emoji indexOf", s(emoji).IndexOf(s("b")), "2"},
+		{"emoji indexOf emoji", s(emoji).IndexOf(s("😀")), "1"},
+
+		{"zwj length", s(family).Length(), "5"},
+		{"zwj graphemeLength", s(family).GraphemeLength(), "1"},
+		{"zwj at joiner", s(family).At(1), strconv.Quote("\u200d")},
+		{"zwj at", s(family).At(2), `"👩"`},
+		{"zwj charCode", s(family).CharCode(4), "128103"},
+		{"zwj charCode out of range", s(family).CharCode(5), "null"},
+		{"zwj cut", s(family).Cut(0, 1), `"👨"`},
+		{"zwj indexOf", s(family).IndexOf(s("👧")), "4"},
+
+		{"flags length", s(flags).Length(), "4"},
+		{"flags graphemeLength", s(flags).GraphemeLength(), "2"},
+		{"flags at", s(flags).At(0), `"🇨"`},
+		{"flags cut", s(flags).Cut(2, 2), `"🇯🇵"`},
+		{"flags charCode", s(flags).CharCode(1), "127475"},
+		{"flags indexOf", s(flags).IndexOf(s("🇯🇵")), "2"},
+		{"flags at NaN", s(flags).At(nan), "null"},
+
+		{"padStart emoji pad", s("ab").PadStart(5, s("😀x")), `"😀x😀ab"`},
+		{"padEnd cjk", s(cjk).PadEnd(7, s("ab")), `"世界你好aba"`},
+		{"padEnd zwj pad", s("a").PadEnd(3, s(family)), strconv.Quote("a\U0001F468\u200d")},
+		{"padStart already long", s(cjk).PadStart(2, s("x")), `"世界你好"`},
+		{"padStart empty pad", s("a").PadStart(3, s("")), `"a"`},
+	}
+	for _, tt := range tests {
+		if got := render(tt.got); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package objects
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StringifyOptions limits how much of a nested value Stringify renders.
+// Containers nested deeper than MaxDepth are abbreviated to [Array] or
+// [Object], and only the first MaxWidth items of each container are
+// written. A limit of zero or less disables it.
+type StringifyOptions struct {
+	MaxDepth int
+	MaxWidth int
+}
+
+// DefaultStringifyOptions are the limits used by Stringify.
+var DefaultStringifyOptions = StringifyOptions{MaxDepth: 32, MaxWidth: 1000}
+
+func Stringify(v any) *KulaString {
+	return StringifyWith(v, DefaultStringifyOptions)
+}
+
+// StringifyWith renders v like Stringify under opts. Containers that
+// contain themselves are written as [Circular], and __proto__ is hidden.
+func StringifyWith(v any, opts StringifyOptions) *KulaString {
+	if s, ok := v.(*KulaString); ok {
+		return s
+	}
+	st := &stringifier{opts: opts, visiting: make(map[any]bool)}
+	st.write(v, 0)
+	str := KulaString(st.sb.String())
+	return &str
+}
+
+type stringifier struct {
+	sb       strings.Builder
+	opts     StringifyOptions
+	visiting map[any]bool
+}
+
+// enter reports whether container v may be expanded at depth, writing its
+// abbreviation otherwise.
+func (st *stringifier) enter(v any, depth int, name string) bool {
+	if st.visiting[v] {
+		st.sb.WriteString("[Circular]")
+		return false
+	}
+	if st.opts.MaxDepth > 0 && depth >= st.opts.MaxDepth {
+		st.sb.WriteString("[" + name + "]")
+		return false
+	}
+	st.visiting[v] = true
+	return true
+}
+
+// more writes the number of items left out by MaxWidth, reporting whether
+// the item at index i should be skipped.
+func (st *stringifier) more(i, n int) bool {
+	if st.opts.MaxWidth <= 0 || i < st.opts.MaxWidth {
+		return false
+	}
+	st.sb.WriteString(",..." + strconv.Itoa(n-i) + " more")
+	return true
+}
+
+func (st *stringifier) write(v any, depth int) {
+	switch val := v.(type) {
+	case nil:
+		st.sb.WriteString("null")
+	case KulaBool:
+		st.sb.WriteString(strconv.FormatBool(bool(val)))
+	case KulaNumber:
+		s := strconv.FormatFloat(float64(val), 'f', 8, 64)
+		st.sb.WriteString(strings.TrimSuffix(strings.TrimRight(s, "0"), "."))
+	case *KulaString:
+		if depth == 0 {
+			st.sb.WriteString(string(*val))
+		} else {
+			st.sb.WriteString(QuoteJSON(string(*val)))
+		}
+	case *KulaArray:
+		if !st.enter(val, depth, "Array") {
+			return
+		}
+		defer delete(st.visiting, val)
+		st.sb.WriteByte('[')
+		for i, item := range *val {
+			if st.more(i, len(*val)) {
+				break
+			}
+			if i > 0 {
+				st.sb.WriteByte(',')
+			}
+			st.write(item, depth+1)
+		}
+		st.sb.WriteByte(']')
+	case *KulaObject:
+		if !st.enter(val, depth, "Object") {
+			return
+		}
+		defer delete(st.visiting, val)
+		st.sb.WriteByte('{')
+		i := 0
+		for _, key := range val.keys {
+			if key == PROTO__ {
+				continue
+			}
+			if st.more(i, val.Len()-countProto(val)) {
+				break
+			}
+			if i > 0 {
+				st.sb.WriteByte(',')
+			}
+			st.sb.WriteString(QuoteJSON(key))
+			st.sb.WriteByte(':')
+			st.write(val.values[key], depth+1)
+			i++
+		}
+		st.sb.WriteByte('}')
+	case *KulaMap:
+		if !st.enter(val, depth, "Map") {
+			return
+		}
+		defer delete(st.visiting, val)
+		st.sb.WriteString("Map{")
+		i := 0
+		for _, entry := range val.entries {
+			if entry.deleted {
+				continue
+			}
+			if st.more(i, val.size) {
+				break
+			}
+			if i > 0 {
+				st.sb.WriteByte(',')
+			}
+			st.write(entry.key, depth+1)
+			st.sb.WriteString("=>")
+			st.write(entry.value, depth+1)
+			i++
+		}
+		st.sb.WriteByte('}')
+	case *KulaSet:
+		if !st.enter(val, depth, "Set") {
+			return
+		}
+		defer delete(st.visiting, val)
+		st.sb.WriteString("Set{")
+		i := 0
+		for _, entry := range val.items.entries {
+			if entry.deleted {
+				continue
+			}
+			if st.more(i, val.items.size) {
+				break
+			}
+			if i > 0 {
+				st.sb.WriteByte(',')
+			}
+			st.write(entry.key, depth+1)
+			i++
+		}
+		st.sb.WriteByte('}')
+	case fmt.Stringer:
+		st.sb.WriteString(val.String())
+	default:
+		st.sb.WriteString("<UnknownValue>")
+	}
+}
+
+func countProto(obj *KulaObject) int {
+	if _, ok := obj.values[PROTO__]; ok {
+		return 1
+	}
+	return 0
+}
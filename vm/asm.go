@@ -0,0 +1,464 @@
+package vm
+
+import (
+	"bufio"
+	"fmt"
+	"gokula/objects"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Kula assembly is a line based text form of a kulac file:
+//
+//	; comment
+//	.symbols
+//		"x"
+//	.literals
+//		BOOL
+//		3.5
+//		"hello"
+//	.main
+//	L0:	LOAD	0
+//		JMPF	L1
+//	L1:
+//	.func 0 1
+//	.loc "main.kula" 3 5
+//		RET
+//
+// Symbols and string literals are Go-quoted, operands are plain integers,
+// jump operands may name a label of the same block, and the operands of
+// .func are the symbol indices of its params. The implicit false, true and
+// null literals are not listed; bare NONE and BOOL entries reproduce the
+// valueless tags of the literal table. The optional debug section is
+// written as .file declarations and .loc markers, which apply to the
+// instructions that follow them.
+
+const (
+	asmNone = iota
+	asmSymbols
+	asmLiterals
+	asmCode
+)
+
+type asmBlock struct {
+	instructions []Instruction
+	labels       map[string]int
+	fixups       map[int]string
+	lines        map[int]int
+	entries      []LineEntry
+}
+
+type assembler struct {
+	kulac   *CompiledFile
+	files   map[string]int
+	section int
+	block   *asmBlock
+	blocks  []*asmBlock
+	line    int
+}
+
+func Assemble(r io.Reader) (*CompiledFile, error) {
+	asm := new(assembler)
+	asm.kulac = new(CompiledFile)
+	asm.kulac.SymbolArray = make([]string, 0)
+	asm.kulac.Literals = []any{objects.KulaBool(false), objects.KulaBool(true), nil}
+	asm.kulac.Chunk = make([]Instruction, 0)
+	asm.kulac.Functions = make([]*FunctionChunk, 0)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		asm.line++
+		tokens, err := tokenize(scanner.Text())
+		if err == nil {
+			err = asm.statement(tokens)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", asm.line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if asm.blocks == nil {
+		return nil, fmt.Errorf("missing .main block")
+	}
+
+	for bIndex, block := range asm.blocks {
+		for index, label := range block.fixups {
+			target, ok := block.labels[label]
+			if !ok {
+				return nil, fmt.Errorf("line %d: undefined label '%s'", block.lines[index], label)
+			}
+			if !fitsOperand(block.instructions[index].Op, target) {
+				return nil, fmt.Errorf("line %d: label '%s' at %d is out of jump range", block.lines[index], label, target)
+			}
+			block.instructions[index].Val = target
+		}
+		if bIndex == 0 {
+			asm.kulac.Chunk = block.instructions
+		} else {
+			asm.kulac.Functions[bIndex-1].Instructions = block.instructions
+		}
+	}
+	if debug := asm.kulac.Debug; debug != nil {
+		debug.Chunk = asm.blocks[0].entries
+		debug.Functions = make([][]LineEntry, len(asm.kulac.Functions))
+		for fIndex := range debug.Functions {
+			debug.Functions[fIndex] = asm.blocks[fIndex+1].entries
+		}
+	}
+	return asm.kulac, nil
+}
+
+func (asm *assembler) statement(tokens []asmToken) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	head := tokens[0]
+	if !head.quoted && strings.HasPrefix(head.text, ".") {
+		return asm.directive(head.text, tokens[1:])
+	}
+
+	switch asm.section {
+	case asmSymbols:
+		if len(tokens) != 1 || !head.quoted {
+			return fmt.Errorf("symbol must be a single quoted string")
+		}
+		if len(head.text) >= int(SEPRATOR) {
+			return fmt.Errorf("symbol is too long")
+		}
+		asm.kulac.SymbolArray = append(asm.kulac.SymbolArray, head.text)
+	case asmLiterals:
+		if len(tokens) != 1 {
+			return fmt.Errorf("expect one literal per line")
+		}
+		return asm.literal(head)
+	case asmCode:
+		return asm.instruction(tokens)
+	default:
+		return fmt.Errorf("statement outside of a section")
+	}
+	return nil
+}
+
+func (asm *assembler) directive(name string, args []asmToken) error {
+	switch name {
+	case ".symbols":
+		asm.section = asmSymbols
+	case ".literals":
+		asm.section = asmLiterals
+	case ".main":
+		if asm.blocks != nil {
+			return fmt.Errorf("duplicated .main block")
+		}
+		asm.beginBlock()
+		return nil
+	case ".func":
+		if asm.blocks == nil {
+			return fmt.Errorf(".func before .main")
+		}
+		function := new(FunctionChunk)
+		function.Params = make([]uint16, len(args))
+		for i, arg := range args {
+			index, err := asm.operand(arg, math.MaxUint16)
+			if err != nil {
+				return err
+			}
+			function.Params[i] = uint16(index)
+		}
+		if len(function.Params) >= int(SEPRATOR) {
+			return fmt.Errorf("too many params")
+		}
+		asm.kulac.Functions = append(asm.kulac.Functions, function)
+		asm.beginBlock()
+		return nil
+	case ".file":
+		if len(args) != 1 || !args[0].quoted {
+			return fmt.Errorf(".file takes one quoted name")
+		}
+		asm.file(args[0].text)
+		return nil
+	case ".loc":
+		if asm.section != asmCode {
+			return fmt.Errorf(".loc outside of a block")
+		}
+		if len(args) != 3 || !args[0].quoted {
+			return fmt.Errorf(".loc takes a quoted file, a line and a column")
+		}
+		line, err := asm.operand(args[1], math.MaxInt32)
+		if err != nil {
+			return err
+		}
+		column, err := asm.operand(args[2], math.MaxInt32)
+		if err != nil {
+			return err
+		}
+		entry := LineEntry{len(asm.block.instructions), asm.file(args[0].text), line, column}
+		if n := len(asm.block.entries); n > 0 && asm.block.entries[n-1].Index == entry.Index {
+			asm.block.entries[n-1] = entry
+		} else {
+			asm.block.entries = append(asm.block.entries, entry)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown directive '%s'", name)
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("directive '%s' takes no operands", name)
+	}
+	return nil
+}
+
+// file returns the index of the debug file name, declaring it if needed.
+func (asm *assembler) file(name string) int {
+	if asm.kulac.Debug == nil {
+		asm.kulac.Debug = new(DebugInfo)
+		asm.files = make(map[string]int)
+	}
+	index, ok := asm.files[name]
+	if !ok {
+		index = len(asm.kulac.Debug.Files)
+		asm.files[name] = index
+		asm.kulac.Debug.Files = append(asm.kulac.Debug.Files, name)
+	}
+	return index
+}
+
+func (asm *assembler) beginBlock() {
+	asm.section = asmCode
+	asm.block = &asmBlock{
+		instructions: make([]Instruction, 0),
+		labels:       make(map[string]int),
+		fixups:       make(map[int]string),
+		lines:        make(map[int]int),
+	}
+	asm.blocks = append(asm.blocks, asm.block)
+}
+
+func (asm *assembler) literal(token asmToken) error {
+	if token.quoted {
+		str := objects.KulaString(token.text)
+		asm.kulac.Literals = append(asm.kulac.Literals, &str)
+		return nil
+	}
+	switch token.text {
+	case "NONE":
+		asm.kulac.placeholders = append(asm.kulac.placeholders, placeholder{len(asm.kulac.Literals), NONE})
+	case "BOOL":
+		asm.kulac.placeholders = append(asm.kulac.placeholders, placeholder{len(asm.kulac.Literals), BOOL})
+	default:
+		number, err := strconv.ParseFloat(token.text, 64)
+		if err != nil {
+			return fmt.Errorf("illegal literal '%s'", token.text)
+		}
+		asm.kulac.Literals = append(asm.kulac.Literals, objects.FromFloat64(number))
+	}
+	return nil
+}
+
+func (asm *assembler) instruction(tokens []asmToken) error {
+	block := asm.block
+	for len(tokens) > 0 && !tokens[0].quoted && strings.HasSuffix(tokens[0].text, ":") {
+		label := strings.TrimSuffix(tokens[0].text, ":")
+		if _, ok := block.labels[label]; ok || label == "" {
+			return fmt.Errorf("duplicated label '%s'", label)
+		}
+		block.labels[label] = len(block.instructions)
+		tokens = tokens[1:]
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	op, ok := ParseOpCode(tokens[0].text)
+	if !ok || tokens[0].quoted {
+		return fmt.Errorf("unknown instruction '%s'", tokens[0].text)
+	}
+	inst := Instruction{Op: op}
+	size := codeSize(op)
+	if size == 0 {
+		if len(tokens) != 1 {
+			return fmt.Errorf("%s takes no operand", op)
+		}
+	} else {
+		if len(tokens) != 2 {
+			return fmt.Errorf("%s takes one operand", op)
+		}
+		operand := tokens[1]
+		switch op {
+		case JMP, JMPT, JMPF, TRY:
+			if _, err := strconv.Atoi(operand.text); err != nil && !operand.quoted {
+				block.fixups[len(block.instructions)] = operand.text
+				block.lines[len(block.instructions)] = asm.line
+				break
+			}
+			fallthrough
+		default:
+			val, err := asm.operand(operand, 1<<size-1)
+			if err != nil {
+				return err
+			}
+			inst.Val = val
+		}
+	}
+	block.instructions = append(block.instructions, inst)
+	return nil
+}
+
+func (asm *assembler) operand(token asmToken, max int) (int, error) {
+	val, err := strconv.Atoi(token.text)
+	if err != nil || token.quoted {
+		return 0, fmt.Errorf("illegal operand '%s'", token.text)
+	}
+	if val < 0 || val > max {
+		return 0, fmt.Errorf("operand %d out of range", val)
+	}
+	return val, nil
+}
+
+type asmToken struct {
+	text   string
+	quoted bool
+}
+
+func tokenize(line string) ([]asmToken, error) {
+	tokens := make([]asmToken, 0)
+	for i := 0; i < len(line); {
+		c := line[i]
+		switch {
+		case c == ';':
+			return tokens, nil
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '"':
+			j := i + 1
+			for ; j < len(line) && line[j] != '"'; j++ {
+				if line[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(line) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			text, err := strconv.Unquote(line[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("illegal string %s", line[i:j+1])
+			}
+			tokens = append(tokens, asmToken{text, true})
+			i = j + 1
+		default:
+			j := i
+			for ; j < len(line) && !strings.ContainsRune(" \t\r;\"", rune(line[j])); j++ {
+			}
+			tokens = append(tokens, asmToken{line[i:j], false})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+// Disassemble writes kulac in the assembly syntax accepted by Assemble.
+func Disassemble(kulac *CompiledFile, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	bw.WriteString(".symbols\n")
+	for i, symbol := range kulac.SymbolArray {
+		fmt.Fprintf(bw, "\t%s\t; %d\n", strconv.Quote(symbol), i)
+	}
+
+	bw.WriteString(".literals\n")
+	placeholders := kulac.placeholders
+	for i := literalBase; i <= len(kulac.Literals); i++ {
+		for len(placeholders) > 0 && placeholders[0].at <= i {
+			if placeholders[0].tag == NONE {
+				bw.WriteString("\tNONE\n")
+			} else {
+				bw.WriteString("\tBOOL\n")
+			}
+			placeholders = placeholders[1:]
+		}
+		if i == len(kulac.Literals) {
+			break
+		}
+		switch literal := kulac.Literals[i].(type) {
+		case *objects.KulaString:
+			fmt.Fprintf(bw, "\t%s\t; %d\n", strconv.Quote(string(*literal)), i)
+		case objects.KulaNumber:
+			fmt.Fprintf(bw, "\t%s\t; %d\n", strconv.FormatFloat(float64(literal), 'g', -1, 64), i)
+		default:
+			return fmt.Errorf("cannot disassemble literal %d of type '%s'", i, *TypeOf(literal))
+		}
+	}
+
+	if kulac.Debug != nil {
+		for _, name := range kulac.Debug.Files {
+			fmt.Fprintf(bw, ".file %s\n", strconv.Quote(name))
+		}
+	}
+
+	bw.WriteString(".main\n")
+	err := kulac.disassembleBlock(bw, -1, kulac.Chunk)
+	if err != nil {
+		return err
+	}
+	for fIndex, function := range kulac.Functions {
+		bw.WriteString(".func")
+		for _, param := range function.Params {
+			fmt.Fprintf(bw, " %d", param)
+		}
+		fmt.Fprintf(bw, "\t; F %d\n", fIndex)
+		err = kulac.disassembleBlock(bw, fIndex, function.Instructions)
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func (kulac *CompiledFile) disassembleBlock(bw *bufio.Writer, fIndex int, instructions []Instruction) error {
+	var entries []LineEntry
+	if kulac.Debug != nil {
+		entries = kulac.Debug.block(fIndex)
+	}
+	targets := make(map[int]bool)
+	for _, inst := range instructions {
+		switch inst.Op {
+		case JMP, JMPT, JMPF, TRY:
+			if inst.Val <= len(instructions) {
+				targets[inst.Val] = true
+			}
+		}
+	}
+	for i := 0; i <= len(instructions); i++ {
+		if targets[i] {
+			fmt.Fprintf(bw, "L%d:\n", i)
+		}
+		for len(entries) > 0 && entries[0].Index <= i {
+			entry := entries[0]
+			if entry.File < 0 || entry.File >= len(kulac.Debug.Files) {
+				return fmt.Errorf("debug file %d out of range", entry.File)
+			}
+			fmt.Fprintf(bw, ".loc %s %d %d\n", strconv.Quote(kulac.Debug.Files[entry.File]), entry.Line, entry.Column)
+			entries = entries[1:]
+		}
+		if i == len(instructions) {
+			break
+		}
+		inst := instructions[i]
+		switch {
+		case inst.Op.String() == "":
+			return fmt.Errorf("cannot disassemble opcode 0x%x", byte(inst.Op))
+		case codeSize(inst.Op) == 0:
+			fmt.Fprintf(bw, "\t%s\n", inst.Op)
+		case targets[inst.Val] && (inst.Op == JMP || inst.Op == JMPT || inst.Op == JMPF || inst.Op == TRY):
+			fmt.Fprintf(bw, "\t%s\tL%d\n", inst.Op, inst.Val)
+		case (inst.Op == LOAD || inst.Op == DECL || inst.Op == ASGN) && inst.Val < len(kulac.SymbolArray):
+			fmt.Fprintf(bw, "\t%s\t%d\t; %s\n", inst.Op, inst.Val, kulac.SymbolArray[inst.Val])
+		default:
+			fmt.Fprintf(bw, "\t%s\t%d\n", inst.Op, inst.Val)
+		}
+	}
+	return nil
+}
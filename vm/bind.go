@@ -0,0 +1,525 @@
+package vm
+
+import (
+	"fmt"
+	"gokula/objects"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Bind converts v with ToKula and defines it as a global.
+func (m *Machine) Bind(name string, v any) error {
+	val, err := m.ToKula(v)
+	if err != nil {
+		return fmt.Errorf("bind '%s': %w", name, err)
+	}
+	m.global.Define(name, val)
+	return nil
+}
+
+// ToKula converts a Go value into a Kula value. Numbers, bools and strings
+// map to their Kula types, slices and arrays to Array, maps with string
+// keys to Object, funcs to native functions, and structs to an Object of
+// their exported fields and methods. Kula values are returned unchanged.
+func (m *Machine) ToKula(v any) (any, error) {
+	b := &binder{m, make(map[uintptr]any), nil}
+	return b.toKula(reflect.ValueOf(v))
+}
+
+// FromKula converts a Kula value into a Go value of type t.
+func (m *Machine) FromKula(v any, t reflect.Type) (reflect.Value, error) {
+	b := &binder{m, nil, make(map[any]bool)}
+	return b.fromKula(v, t)
+}
+
+// Decode converts a Kula value into a Go value of type T.
+func Decode[T any](m *Machine, v any) (zero T, err error) {
+	val, err := m.FromKula(v, reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return zero, err
+	}
+	return val.Interface().(T), nil
+}
+
+type binder struct {
+	m *Machine
+	// seen maps Go pointers to the Kula values made from them, so that
+	// shared and cyclic Go data keep their shape.
+	seen map[uintptr]any
+	// visiting holds the Kula containers being decoded, to reject cycles.
+	visiting map[any]bool
+}
+
+var (
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+	anyType   = reflect.TypeOf((*any)(nil)).Elem()
+)
+
+func isKulaValue(v any) bool {
+	switch v.(type) {
+	case objects.KulaBool, objects.KulaNumber, *objects.KulaString, *objects.KulaArray,
+		*objects.KulaObject, *objects.KulaMap, *objects.KulaSet, *VMFunction, *NativeFunction:
+		return true
+	}
+	return false
+}
+
+func (b *binder) toKula(rv reflect.Value) (any, error) {
+	if !rv.IsValid() {
+		return nil, nil
+	}
+	if rv.CanInterface() && isKulaValue(rv.Interface()) {
+		return rv.Interface(), nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return objects.KulaBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return objects.KulaNumber(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return objects.KulaNumber(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return objects.KulaNumber(rv.Float()), nil
+	case reflect.String:
+		str := objects.KulaString(rv.String())
+		return &str, nil
+	case reflect.Interface:
+		return b.toKula(rv.Elem())
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil, nil
+		}
+		slice := make([]any, rv.Len())
+		for i := range slice {
+			item, err := b.toKula(rv.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %w", i, err)
+			}
+			slice[i] = item
+		}
+		return objects.FromSlice(slice), nil
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("cannot convert %s, map keys must be strings", rv.Type())
+		}
+		// sorted, so that the object does not inherit the random map order
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		obj := objects.NewObject()
+		for _, k := range keys {
+			key := objects.KulaString(k.String())
+			value, err := b.toKula(rv.MapIndex(k))
+			if err != nil {
+				return nil, fmt.Errorf("key '%s': %w", key, err)
+			}
+			obj.Set(&key, value)
+		}
+		return obj, nil
+	case reflect.Func:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return b.bindFunc(rv, ""), nil
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		if val, ok := b.seen[rv.Pointer()]; ok {
+			return val, nil
+		}
+		if rv.Elem().Kind() == reflect.Struct {
+			return b.bindStruct(rv)
+		}
+		return b.toKula(rv.Elem())
+	case reflect.Struct:
+		ptr := reflect.New(rv.Type())
+		ptr.Elem().Set(rv)
+		return b.bindStruct(ptr)
+	}
+	return nil, fmt.Errorf("cannot convert Go value of type %s", rv.Type())
+}
+
+type structField struct {
+	name  string
+	index int
+}
+
+// structFields lists the exported fields of t in declaration order by their
+// Kula names, which come from a `kula:"name"` tag or default to the Go name.
+func structFields(t reflect.Type) []structField {
+	fields := make([]structField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("kula"); ok {
+			if tag == "-" {
+				continue
+			}
+			name = tag
+		}
+		fields = append(fields, structField{name, i})
+	}
+	return fields
+}
+
+// bindStruct converts the struct behind ptr into an Object holding its
+// fields and methods. Calling a method first stores the fields of `this`
+// back into the struct and afterwards refreshes them from it.
+func (b *binder) bindStruct(ptr reflect.Value) (any, error) {
+	obj := objects.NewObject()
+	b.seen[ptr.Pointer()] = obj
+	fields := structFields(ptr.Elem().Type())
+
+	err := b.storeFields(obj, ptr.Elem(), fields)
+	if err != nil {
+		return nil, err
+	}
+
+	t := ptr.Type()
+	for i := 0; i < t.NumMethod(); i++ {
+		method := t.Method(i)
+		bound := ptr.Method(i)
+		native := b.bindFunc(bound, method.Name)
+		callee := native.Callee
+		native.Callee = func(this any, argv []any) (any, error) {
+			if self, ok := this.(*objects.KulaObject); ok {
+				err := b.m.loadFields(self, ptr.Elem(), fields)
+				if err != nil {
+					return nil, err
+				}
+				defer (&binder{b.m, make(map[uintptr]any), nil}).storeFields(self, ptr.Elem(), fields)
+			}
+			return callee(this, argv)
+		}
+		obj.SetNative(method.Name, native)
+	}
+	return obj, nil
+}
+
+func (b *binder) storeFields(obj *objects.KulaObject, sv reflect.Value, fields []structField) error {
+	for _, field := range fields {
+		name, index := field.name, field.index
+		value, err := b.toKula(sv.Field(index))
+		if err != nil {
+			return fmt.Errorf("field %s: %w", name, err)
+		}
+		obj.SetNative(name, value)
+	}
+	return nil
+}
+
+func (m *Machine) loadFields(obj *objects.KulaObject, sv reflect.Value, fields []structField) error {
+	for _, field := range fields {
+		name, index := field.name, field.index
+		item, _ := obj.Own(name)
+		value, err := m.FromKula(item, sv.Field(index).Type())
+		if err != nil {
+			return fmt.Errorf("field %s: %w", name, err)
+		}
+		sv.Field(index).Set(value)
+	}
+	return nil
+}
+
+// bindFunc wraps a Go func as a native function, decoding its arguments
+// with FromKula and encoding its results with ToKula. A trailing error
+// result becomes the error of the call, and several other results are
+// returned as an Array.
+func (b *binder) bindFunc(fn reflect.Value, name string) *NativeFunction {
+	t := fn.Type()
+	arity := t.NumIn()
+	if t.IsVariadic() {
+		arity--
+	}
+	if name == "" {
+		name = t.String()
+	}
+
+	return NewNativeFunction(
+		func(this any, argv []any) (result any, err error) {
+			if len(argv) < arity || (!t.IsVariadic() && len(argv) > arity) {
+				return nil, fmt.Errorf("%s expects %d argument(s) but %d given", name, arity, len(argv))
+			}
+			in := make([]reflect.Value, len(argv))
+			for i, arg := range argv {
+				var pt reflect.Type
+				if t.IsVariadic() && i >= arity {
+					pt = t.In(arity).Elem()
+				} else {
+					pt = t.In(i)
+				}
+				in[i], err = b.m.FromKula(arg, pt)
+				if err != nil {
+					return nil, fmt.Errorf("%s argument %d: %w", name, i, err)
+				}
+			}
+
+			defer func() {
+				if r := recover(); r != nil {
+					result, err = nil, fmt.Errorf("%s panicked: %v", name, r)
+				}
+			}()
+			out := fn.Call(in)
+
+			if len(out) > 0 && t.Out(len(out)-1) == errorType {
+				last := out[len(out)-1]
+				out = out[:len(out)-1]
+				if !last.IsNil() {
+					return nil, last.Interface().(error)
+				}
+			}
+			switch len(out) {
+			case 0:
+				return nil, nil
+			case 1:
+				return b.m.ToKula(out[0].Interface())
+			default:
+				results := make([]any, len(out))
+				for i, o := range out {
+					results[i], err = b.m.ToKula(o.Interface())
+					if err != nil {
+						return nil, err
+					}
+				}
+				return objects.FromSlice(results), nil
+			}
+		}, int8(min(arity, math.MaxInt8)),
+	)
+}
+
+func (b *binder) fromKula(v any, t reflect.Type) (reflect.Value, error) {
+	fail := func() (reflect.Value, error) {
+		return reflect.Value{}, fmt.Errorf("cannot convert %s to %s", *TypeOf(v), t)
+	}
+
+	if t == anyType {
+		return b.natural(v)
+	}
+	if v == nil {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func:
+			return reflect.Zero(t), nil
+		}
+		return fail()
+	}
+	if rv := reflect.ValueOf(v); rv.Type().AssignableTo(t) {
+		return rv, nil
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		// pointers convert the same value again for their element type
+		if b.enter(v) {
+			return reflect.Value{}, fmt.Errorf("cannot convert cyclic %s", *TypeOf(v))
+		}
+		defer delete(b.visiting, v)
+	}
+
+	out := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Bool:
+		val, ok := v.(objects.KulaBool)
+		if !ok {
+			return fail()
+		}
+		out.SetBool(bool(val))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		val, ok := v.(objects.KulaNumber)
+		if !ok || float64(val) != math.Trunc(float64(val)) || out.OverflowInt(int64(val)) {
+			return fail()
+		}
+		out.SetInt(int64(val))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		val, ok := v.(objects.KulaNumber)
+		if !ok || val < 0 || float64(val) != math.Trunc(float64(val)) || out.OverflowUint(uint64(val)) {
+			return fail()
+		}
+		out.SetUint(uint64(val))
+	case reflect.Float32, reflect.Float64:
+		val, ok := v.(objects.KulaNumber)
+		if !ok {
+			return fail()
+		}
+		out.SetFloat(float64(val))
+	case reflect.String:
+		val, ok := v.(*objects.KulaString)
+		if !ok {
+			return fail()
+		}
+		out.SetString(string(*val))
+	case reflect.Slice, reflect.Array:
+		arr, ok := v.(*objects.KulaArray)
+		if !ok {
+			return fail()
+		}
+		if t.Kind() == reflect.Slice {
+			out = reflect.MakeSlice(t, len(*arr), len(*arr))
+		} else if t.Len() != len(*arr) {
+			return reflect.Value{}, fmt.Errorf("cannot convert Array of length %d to %s", len(*arr), t)
+		}
+		for i, item := range *arr {
+			elem, err := b.fromKula(item, t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("index %d: %w", i, err)
+			}
+			out.Index(i).Set(elem)
+		}
+	case reflect.Map:
+		obj, ok := v.(*objects.KulaObject)
+		if !ok || t.Key().Kind() != reflect.String {
+			return fail()
+		}
+		out = reflect.MakeMapWithSize(t, obj.Len())
+		for _, key := range obj.Keys() {
+			if key == objects.PROTO__ {
+				continue
+			}
+			item, _ := obj.Own(key)
+			elem, err := b.fromKula(item, t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("key '%s': %w", key, err)
+			}
+			out.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), elem)
+		}
+	case reflect.Struct:
+		obj, ok := v.(*objects.KulaObject)
+		if !ok {
+			return fail()
+		}
+		for _, field := range structFields(t) {
+			name, index := field.name, field.index
+			item, ok := obj.Own(name)
+			if !ok {
+				continue
+			}
+			elem, err := b.fromKula(item, t.Field(index).Type)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("field %s: %w", name, err)
+			}
+			out.Field(index).Set(elem)
+		}
+	case reflect.Func:
+		switch v.(type) {
+		case *VMFunction, *NativeFunction, *objects.KulaObject:
+		default:
+			return fail()
+		}
+		out = reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+			return b.m.callFromGo(v, t, in)
+		})
+	case reflect.Pointer:
+		elem, err := b.fromKula(v, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out = reflect.New(t.Elem())
+		out.Elem().Set(elem)
+	default:
+		return fail()
+	}
+	return out, nil
+}
+
+// callFromGo backs Go funcs made from Kula functions. A trailing error
+// result receives failures; without one the other results stay zero.
+func (m *Machine) callFromGo(fn any, t reflect.Type, in []reflect.Value) []reflect.Value {
+	out := make([]reflect.Value, t.NumOut())
+	for i := range out {
+		out[i] = reflect.Zero(t.Out(i))
+	}
+	setErr := func(err error) []reflect.Value {
+		if len(out) > 0 && t.Out(len(out)-1) == errorType {
+			out[len(out)-1] = reflect.ValueOf(&err).Elem()
+		}
+		return out
+	}
+
+	args := make([]any, len(in))
+	for i, arg := range in {
+		args[i] = arg.Interface()
+	}
+	result, err := m.Call(fn, nil, args...)
+	if err != nil {
+		return setErr(err)
+	}
+	if len(out) > 0 && t.Out(0) != errorType {
+		val, err := m.FromKula(result, t.Out(0))
+		if err != nil {
+			return setErr(err)
+		}
+		out[0] = val
+	}
+	return out
+}
+
+// natural converts a Kula value into the Go value an `any` should hold:
+// float64, bool, string, []any, map[string]any, or the function itself.
+func (b *binder) natural(v any) (reflect.Value, error) {
+	var out any
+	switch val := v.(type) {
+	case nil:
+		return reflect.Zero(anyType), nil
+	case objects.KulaBool:
+		out = bool(val)
+	case objects.KulaNumber:
+		out = float64(val)
+	case *objects.KulaString:
+		out = string(*val)
+	case *objects.KulaArray:
+		if b.enter(v) {
+			return reflect.Value{}, fmt.Errorf("cannot convert cyclic Array")
+		}
+		defer delete(b.visiting, v)
+		slice := make([]any, len(*val))
+		for i, item := range *val {
+			elem, err := b.natural(item)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			slice[i] = elem.Interface()
+		}
+		out = slice
+	case *objects.KulaObject:
+		if b.enter(v) {
+			return reflect.Value{}, fmt.Errorf("cannot convert cyclic Object")
+		}
+		defer delete(b.visiting, v)
+		m := make(map[string]any, val.Len())
+		for _, key := range val.Keys() {
+			if key == objects.PROTO__ {
+				continue
+			}
+			item, _ := val.Own(key)
+			elem, err := b.natural(item)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			m[key] = elem.Interface()
+		}
+		out = m
+	default:
+		out = v
+	}
+	rv := reflect.New(anyType).Elem()
+	rv.Set(reflect.ValueOf(out))
+	return rv, nil
+}
+
+// enter marks a container as being decoded and reports whether it already was.
+func (b *binder) enter(v any) bool {
+	switch v.(type) {
+	case *objects.KulaArray, *objects.KulaObject:
+		if b.visiting[v] {
+			return true
+		}
+		b.visiting[v] = true
+	}
+	return false
+}
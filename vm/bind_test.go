@@ -0,0 +1,161 @@
+package vm
+
+import (
+	"gokula/objects"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type bindConfig struct {
+	Name  string
+	Port  int `kula:"port"`
+	Tags  []string
+	Limit *float64
+	Extra map[string]bool
+	skip  int
+}
+
+func TestDecode(t *testing.T) {
+	m := runSource(t, ".main\n")
+	kula := func(v any) any {
+		t.Helper()
+		val, err := m.ToKula(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return val
+	}
+	config := func() *objects.KulaObject {
+		obj := objects.NewObject()
+		obj.SetNative("Name", str("srv"))
+		obj.SetNative("port", objects.KulaNumber(8080))
+		obj.SetNative("Tags", objects.FromSlice([]any{str("a"), str("b")}))
+		obj.SetNative("Limit", objects.KulaNumber(1.5))
+		obj.SetNative("Extra", kula(map[string]bool{"x": true}))
+		return obj
+	}
+	limit := 1.5
+	want := bindConfig{"srv", 8080, []string{"a", "b"}, &limit, map[string]bool{"x": true}, 0}
+
+	t.Run("struct", func(t *testing.T) {
+		got, err := Decode[bindConfig](m, config())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+	t.Run("pointer to struct", func(t *testing.T) {
+		got, err := Decode[*bindConfig](m, config())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(*got, want) {
+			t.Errorf("got %+v, want %+v", *got, want)
+		}
+	})
+	t.Run("pointer to slice", func(t *testing.T) {
+		got, err := Decode[*[]int](m, numbers(1, 2, 3))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(*got, []int{1, 2, 3}) {
+			t.Errorf("got %v", *got)
+		}
+	})
+	t.Run("nil pointer", func(t *testing.T) {
+		got, err := Decode[*int](m, nil)
+		if err != nil || got != nil {
+			t.Errorf("got %v, %v, want nil", got, err)
+		}
+	})
+	t.Run("slice", func(t *testing.T) {
+		got, err := Decode[[]float64](m, numbers(1, 2.5))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, []float64{1, 2.5}) {
+			t.Errorf("got %v", got)
+		}
+	})
+	t.Run("array", func(t *testing.T) {
+		got, err := Decode[[2]int](m, numbers(4, 5))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != [2]int{4, 5} {
+			t.Errorf("got %v", got)
+		}
+	})
+	t.Run("map", func(t *testing.T) {
+		got, err := Decode[map[string]int](m, kula(map[string]int{"a": 1, "b": 2}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, map[string]int{"a": 1, "b": 2}) {
+			t.Errorf("got %v", got)
+		}
+	})
+	t.Run("shared element", func(t *testing.T) {
+		inner := numbers(1)
+		got, err := Decode[[][]int](m, objects.FromSlice([]any{inner, inner}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, [][]int{{1}, {1}}) {
+			t.Errorf("got %v", got)
+		}
+	})
+	t.Run("bound func taking a pointer", func(t *testing.T) {
+		err := m.Bind("port", func(c *bindConfig) int { return c.Port })
+		if err != nil {
+			t.Fatal(err)
+		}
+		fn, _ := m.Global().Get("port")
+		got, err := m.Call(fn, nil, config())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != objects.KulaNumber(8080) {
+			t.Errorf("got %v, want 8080", got)
+		}
+	})
+}
+
+func TestDecodeErrors(t *testing.T) {
+	m := runSource(t, ".main\n")
+	cyclic := objects.NewArray()
+	cyclic.Push(cyclic)
+	wrongField := objects.NewObject()
+	wrongField.SetNative("port", str("80"))
+
+	tests := []struct {
+		name string
+		v    any
+		t    reflect.Type
+		err  string
+	}{
+		{"string to int", str("1"), reflect.TypeOf(0), "cannot convert String to int"},
+		{"fraction to int", objects.KulaNumber(1.5), reflect.TypeOf(0), "cannot convert Number to int"},
+		{"overflow", objects.KulaNumber(300), reflect.TypeOf(uint8(0)), "cannot convert Number to uint8"},
+		{"negative to uint", objects.KulaNumber(-1), reflect.TypeOf(uint(0)), "cannot convert Number to uint"},
+		{"nil to int", nil, reflect.TypeOf(0), "cannot convert None to int"},
+		{"array length", numbers(1), reflect.TypeOf([2]int{}), "cannot convert Array of length 1 to [2]int"},
+		{"slice element", objects.FromSlice([]any{objects.KulaNumber(1), str("x")}), reflect.TypeOf([]int{}), "index 1: cannot convert String to int"},
+		{"map value", wrongField, reflect.TypeOf(map[string]int{}), "key 'port': cannot convert String to int"},
+		{"struct field", wrongField, reflect.TypeOf(bindConfig{}), "field port: cannot convert String to int"},
+		{"pointer element", str("x"), reflect.TypeOf((*bindConfig)(nil)), "cannot convert String to vm.bindConfig"},
+		{"cyclic", cyclic, reflect.TypeOf([]any{}), "cannot convert cyclic Array"},
+		{"cyclic through pointer", cyclic, reflect.TypeOf((*[]any)(nil)), "cannot convert cyclic Array"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.FromKula(tt.v, tt.t)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -31,7 +31,7 @@ func (ctx *Context) Assgin(key string, value any) error {
 		ctx.values[key] = value
 		return nil
 	}
-	if ctx.enclosing != ctx {
+	if ctx.enclosing != nil {
 		return ctx.enclosing.Assgin(key, value)
 	}
 	return fmt.Errorf("undefined variable '%s' when assign", key)
@@ -0,0 +1,67 @@
+package vm
+
+import (
+	"fmt"
+	"sort"
+)
+
+type SourcePos struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (pos SourcePos) String() string {
+	return fmt.Sprintf("%s:%d:%d", pos.File, pos.Line, pos.Column)
+}
+
+// LineEntry maps the instructions from Index up to the next entry of the
+// same block to a position in Files.
+type LineEntry struct {
+	Index  int
+	File   int
+	Line   int
+	Column int
+}
+
+// lineRecord is the encoded form of a LineEntry.
+type lineRecord struct {
+	Index  uint32
+	File   uint16
+	Line   uint32
+	Column uint32
+}
+
+// DebugInfo is the optional debug section of a kulac file. Its blocks are
+// parallel to CompiledFile.Chunk and CompiledFile.Functions, and every
+// block keeps its entries sorted by Index.
+type DebugInfo struct {
+	Files     []string
+	Chunk     []LineEntry
+	Functions [][]LineEntry
+}
+
+func (d *DebugInfo) block(fp int) []LineEntry {
+	if fp < 0 {
+		return d.Chunk
+	}
+	if fp < len(d.Functions) {
+		return d.Functions[fp]
+	}
+	return nil
+}
+
+// Position returns the source position of instruction ip in function fp,
+// or in the main chunk when fp is negative.
+func (d *DebugInfo) Position(fp, ip int) (SourcePos, bool) {
+	if d == nil {
+		return SourcePos{}, false
+	}
+	entries := d.block(fp)
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].Index > ip }) - 1
+	if i < 0 || entries[i].File < 0 || entries[i].File >= len(d.Files) {
+		return SourcePos{}, false
+	}
+	entry := entries[i]
+	return SourcePos{d.Files[entry.File], entry.Line, entry.Column}, true
+}
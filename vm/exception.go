@@ -0,0 +1,113 @@
+package vm
+
+import (
+	"errors"
+	"gokula/objects"
+)
+
+// handler is installed by TRY and removed by ENDTRY. It records enough of
+// the machine to unwind back to the frame that installed it.
+type handler struct {
+	target    int
+	fp        int
+	callDepth int
+	stackSize int
+	context   *Context
+}
+
+// ThrownError carries a Kula value thrown by THROW or by a native function
+// through Go code until a handler catches it.
+type ThrownError struct {
+	Value   any
+	Message string
+}
+
+func (e *ThrownError) Error() string {
+	return "uncaught " + e.Message
+}
+
+func (m *Machine) newError(message string) *objects.KulaObject {
+	obj := objects.NewObject()
+	obj.SetNative(objects.PROTO__, m.errorProto)
+	str := objects.KulaString(message)
+	obj.SetNative("message", &str)
+	obj.SetNative("trace", nil)
+	return obj
+}
+
+func (m *Machine) isError(v any) (*objects.KulaObject, bool) {
+	obj, ok := v.(*objects.KulaObject)
+	if !ok {
+		return nil, false
+	}
+	raw, _ := obj.Own(objects.PROTO__)
+	proto, _ := raw.(*objects.KulaObject)
+	return obj, proto == m.errorProto
+}
+
+// newThrownError wraps value for THROW, filling in the trace of an Error
+// object that has not been thrown before.
+func (m *Machine) newThrownError(value any) *ThrownError {
+	message := string(*objects.Stringify(value))
+	if obj, ok := m.isError(value); ok {
+		msg, _ := obj.Own("message")
+		message = string(*objects.Stringify(msg))
+		if trace, _ := obj.Own("trace"); trace == nil {
+			obj.SetNative("trace", m.traceArray())
+		}
+	}
+	return &ThrownError{value, message}
+}
+
+func (m *Machine) traceArray() *objects.KulaArray {
+	return traceArray(m.newRuntimeError(nil).Trace)
+}
+
+func traceArray(trace []Frame) *objects.KulaArray {
+	lines := make([]any, len(trace))
+	for i, frame := range trace {
+		line := objects.KulaString(frame.String())
+		lines[i] = &line
+	}
+	return objects.FromSlice(lines)
+}
+
+// throw unwinds to the innermost handler above base and pushes the error
+// value for it. It reports false when err is not catchable or no such
+// handler is installed.
+func (m *Machine) throw(err error, base int) bool {
+	if m.handlers.Empty() || m.handlers.Peek().callDepth <= base || !catchable(err) {
+		return false
+	}
+
+	var value any
+	var thrown *ThrownError
+	var runtimeError *RuntimeError
+	if errors.As(err, &thrown) {
+		value = thrown.Value
+	} else if errors.As(err, &runtimeError) {
+		// failed inside a nested Call, which already recorded the trace
+		obj := m.newError(runtimeError.Err.Error())
+		obj.SetNative("trace", traceArray(runtimeError.Trace))
+		value = obj
+	} else {
+		obj := m.newError(err.Error())
+		obj.SetNative("trace", m.traceArray())
+		value = obj
+	}
+
+	h := m.handlers.Pop()
+	for m.callStack.Size() > h.callDepth {
+		m.vmStack.Pop().Clear()
+		m.callStack.Pop()
+	}
+	m.currentStack = m.vmStack.Peek()
+	if m.currentStack.Size() > h.stackSize {
+		*m.currentStack = (*m.currentStack)[:h.stackSize]
+	}
+	m.currentStack.Push(value)
+	m.context = h.context
+	m.fp = h.fp
+	m.ip = h.target - 1
+	return true
+}
@@ -9,7 +9,7 @@ type VMFunction struct {
 func NewFunction(index int, parent *Context) *VMFunction {
 	f := new(VMFunction)
 	f.Index = index
-	f.Parent = context
+	f.Parent = parent
 	f.CallSite = nil
 	return f
 }
@@ -0,0 +1,74 @@
+package vm
+
+import (
+	"fmt"
+	"gokula/objects"
+	"sort"
+)
+
+// Register defines fn as a global native function. The function receives
+// every argument of the call; use CheckArity and Arg to validate them.
+func (m *Machine) Register(name string, fn NativeLambda) {
+	m.global.Define(name, NewNativeFunction(fn, -1))
+}
+
+// RegisterModule defines a global object whose keys are the given natives,
+// so that scripts call them as name.key(...). The keys are sorted so that
+// the object does not inherit the random order of the Go map.
+func (m *Machine) RegisterModule(name string, fns map[string]NativeLambda) {
+	keys := make([]string, 0, len(fns))
+	for key := range fns {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	module := objects.NewObject()
+	for _, key := range keys {
+		module.SetNative(key, NewNativeFunction(fns[key], -1))
+	}
+	m.global.Define(name, module)
+}
+
+// CheckArity fails unless exactly n arguments were given.
+func CheckArity(argv []any, n int) error {
+	if len(argv) != n {
+		return fmt.Errorf("expect %d argument(s) but %d given", n, len(argv))
+	}
+	return nil
+}
+
+// CheckArityRange fails unless between min and max arguments were given.
+func CheckArityRange(argv []any, min, max int) error {
+	if len(argv) < min || len(argv) > max {
+		return fmt.Errorf("expect %d to %d argument(s) but %d given", min, max, len(argv))
+	}
+	return nil
+}
+
+// Arg decodes argument i as T, e.g. Arg[objects.KulaNumber](argv, 0).
+func Arg[T any](argv []any, i int) (zero T, err error) {
+	if i < 0 || i >= len(argv) {
+		return zero, fmt.Errorf("missing argument %d", i)
+	}
+	val, err := assert[T](argv[i])
+	if err != nil {
+		return zero, fmt.Errorf("argument %d: %w", i, err)
+	}
+	return val, nil
+}
+
+// OptArg decodes argument i as T, returning def when it is absent or null.
+func OptArg[T any](argv []any, i int, def T) (T, error) {
+	if i >= len(argv) || argv[i] == nil {
+		return def, nil
+	}
+	return Arg[T](argv, i)
+}
+
+// This decodes the receiver of a method call as T.
+func This[T any](this any) (T, error) {
+	val, err := assert[T](this)
+	if err != nil {
+		return val, fmt.Errorf("receiver: %w", err)
+	}
+	return val, nil
+}
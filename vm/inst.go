@@ -28,6 +28,9 @@ const (
 	GET
 	SET
 	GETWT
+	TRY
+	ENDTRY
+	THROW
 )
 
 const (
@@ -88,6 +91,12 @@ func (op OpCode) String() string {
 		return "SET"
 	case GETWT:
 		return "GETWT"
+	case TRY:
+		return "TRY"
+	case ENDTRY:
+		return "ENDTRY"
+	case THROW:
+		return "THROW"
 	case ADD:
 		return "ADD"
 	case SUB:
@@ -120,3 +129,12 @@ func (op OpCode) String() string {
 		return ""
 	}
 }
+
+func ParseOpCode(name string) (OpCode, bool) {
+	for op := 0; op < int(SEPRATOR); op++ {
+		if name != "" && OpCode(op).String() == name {
+			return OpCode(op), true
+		}
+	}
+	return 0, false
+}
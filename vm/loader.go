@@ -1,6 +1,8 @@
 package vm
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"gokula/objects"
@@ -18,21 +20,46 @@ const (
 	STRING       byte   = 0x83
 )
 
-var CompiledFileInstance *CompiledFile
-
 type FunctionChunk struct {
 	Params       []uint16
 	Instructions []Instruction
 }
 
+// literalBase is the number of implicit literals (false, true, null)
+// that precede the ones stored in a kulac file.
+const literalBase = 3
+
 type CompiledFile struct {
 	SymbolArray []string
 	Literals    []any
 	Chunk       []Instruction
 	Functions   []*FunctionChunk
+	Debug       *DebugInfo // nil when the file has no debug section
+
+	// placeholders records NONE/BOOL tags of the literal table, which carry
+	// no value but must be written back for a byte-exact round trip.
+	placeholders []placeholder
+}
+
+type placeholder struct {
+	at  int
+	tag byte
 }
 
 func Load(path string) (*CompiledFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open file \"%s\": %w", path, err)
+	}
+	defer file.Close()
+	return LoadReader(bufio.NewReader(file))
+}
+
+func LoadBytes(data []byte) (*CompiledFile, error) {
+	return LoadReader(bytes.NewReader(data))
+}
+
+func LoadReader(reader io.Reader) (*CompiledFile, error) {
 	compiledFile := new(CompiledFile)
 
 	compiledFile.SymbolArray = make([]string, 0)
@@ -41,141 +68,304 @@ func Load(path string) (*CompiledFile, error) {
 	compiledFile.Functions = make([]*FunctionChunk, 0)
 
 	var err error
-
-	file, err := os.Open(path)
-	if err != nil {
-		fmt.Printf("Cannot open file \"%s\"\n%s", path, err.Error())
-		return nil, err
-	}
-	defer file.Close()
+	file := &decoder{reader: reader, function: -1}
 
 	// Read Magic Number
+	file.begin("header")
 	var magic_number uint16
-	err = binary.Read(file, binary.LittleEndian, &magic_number)
+	err = file.read(&magic_number)
 	if err != nil {
-		return nil, err
+		return nil, file.fail(err)
 	}
 	if magic_number != MAGIC_NUMBER {
-		return nil, fmt.Errorf("not a kulac file")
+		return nil, file.fail(fmt.Errorf("not a kulac file"))
 	}
 
 	var byte_buffer uint8
 	// Read Signal Table
+	file.begin("symbols")
 	for {
-		err := binary.Read(file, binary.LittleEndian, &byte_buffer)
+		file.mark()
+		err = file.read(&byte_buffer)
 		if err != nil {
-			return compiledFile, err
+			return nil, file.fail(err)
 		}
 		if byte_buffer == SEPRATOR {
 			break
-		} else {
-			bytes := make([]byte, int32(byte_buffer))
-			err = binary.Read(file, binary.LittleEndian, &bytes)
-			if err != nil {
-				return nil, err
-			}
-			symbol := string(bytes)
-			compiledFile.SymbolArray = append(compiledFile.SymbolArray, symbol)
 		}
+		if len(compiledFile.SymbolArray) >= MaxSymbols {
+			return nil, file.fail(fmt.Errorf("more than %d symbols", MaxSymbols))
+		}
+		bytes := make([]byte, int(byte_buffer))
+		err = file.read(bytes)
+		if err != nil {
+			return nil, file.fail(err)
+		}
+		compiledFile.SymbolArray = append(compiledFile.SymbolArray, string(bytes))
 	}
 
 	// Read Literals
-	compiledFile.Literals = append(compiledFile.Literals, false, true, nil)
+	file.begin("literals")
+	compiledFile.Literals = append(compiledFile.Literals, objects.KulaBool(false), objects.KulaBool(true), nil)
 	for {
-		err = binary.Read(file, binary.LittleEndian, &byte_buffer)
+		file.mark()
+		err = file.read(&byte_buffer)
 		if err != nil {
-			return nil, err
+			return nil, file.fail(err)
 		}
 		if byte_buffer == SEPRATOR {
 			break
 		}
+		if len(compiledFile.Literals) >= MaxLiterals {
+			return nil, file.fail(fmt.Errorf("more than %d literals", MaxLiterals))
+		}
 
 		switch byte_buffer {
 		case NONE, BOOL:
+			compiledFile.placeholders = append(compiledFile.placeholders, placeholder{len(compiledFile.Literals), byte_buffer})
 		case STRING:
 			var bytes_size int32
-			err = binary.Read(file, binary.LittleEndian, &bytes_size)
+			err = file.read(&bytes_size)
 			if err != nil {
-				return nil, err
+				return nil, file.fail(err)
 			}
-			bytes := make([]byte, bytes_size)
-			err = binary.Read(file, binary.LittleEndian, bytes)
+			if bytes_size < 0 || bytes_size > MaxStringLength {
+				return nil, file.fail(fmt.Errorf("illegal string length %d", bytes_size))
+			}
+			bytes, err := file.readBytes(int(bytes_size))
 			if err != nil {
-				return nil, err
+				return nil, file.fail(err)
 			}
 			literal := objects.KulaString(bytes)
 			compiledFile.Literals = append(compiledFile.Literals, &literal)
 		case DOUBLE:
 			var number objects.KulaNumber
-			err = binary.Read(file, binary.LittleEndian, &number)
+			err = file.read(&number)
 			if err != nil {
-				return nil, err
+				return nil, file.fail(err)
 			}
 			compiledFile.Literals = append(compiledFile.Literals, number)
 		default:
-			return nil, fmt.Errorf("undefined literal type")
+			return nil, file.fail(fmt.Errorf("undefined literal type 0x%x", byte_buffer))
 		}
 	}
 
 	// Read Instructions
+	file.begin("chunk")
+	compiledFile.Chunk, err = file.readInstructions()
+	if err != nil {
+		return nil, file.fail(err)
+	}
+
+	// Read Functions
+	for {
+		file.begin("function")
+		file.function = len(compiledFile.Functions)
+		err = file.read(&byte_buffer)
+		if err == io.EOF {
+			return compiledFile, nil
+		}
+		if err != nil {
+			return nil, file.fail(err)
+		}
+		if byte_buffer == SEPRATOR {
+			file.begin("debug")
+			compiledFile.Debug, err = file.readDebug(len(compiledFile.Functions))
+			if err != nil {
+				return nil, file.fail(err)
+			}
+			return compiledFile, nil
+		}
+		if len(compiledFile.Functions) >= MaxFunctions {
+			return nil, file.fail(fmt.Errorf("more than %d functions", MaxFunctions))
+		}
+
+		param_size := int(byte_buffer)
+		function := new(FunctionChunk)
+		function.Params = make([]uint16, param_size)
+		err = file.read(&function.Params)
+		if err != nil {
+			return nil, file.fail(err)
+		}
+		function.Instructions, err = file.readInstructions()
+		if err != nil {
+			return nil, file.fail(err)
+		}
+		compiledFile.Functions = append(compiledFile.Functions, function)
+	}
+}
+
+// Limits applied by the loader, so that a malformed or hostile file cannot
+// make it allocate unbounded memory.
+const (
+	MaxSymbols      = 1 << 16
+	MaxLiterals     = 1 << 16
+	MaxStringLength = 1 << 24
+	MaxInstructions = 1 << 20
+	MaxFunctions    = 1 << 16
+)
+
+type LoadError struct {
+	Section  string // header, symbols, literals, chunk, function or debug
+	Function int    // index of the function when Section is function
+	Offset   int64  // byte offset of the item that failed to decode
+	Err      error
+}
+
+func (e *LoadError) Error() string {
+	section := e.Section
+	if e.Section == "function" {
+		section = fmt.Sprintf("function %d", e.Function)
+	}
+	return fmt.Sprintf("cannot load %s at byte %d: %s", section, e.Offset, e.Err.Error())
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+type decoder struct {
+	reader   io.Reader
+	offset   int64
+	start    int64
+	section  string
+	function int
+}
+
+func (d *decoder) Read(p []byte) (int, error) {
+	n, err := d.reader.Read(p)
+	d.offset += int64(n)
+	return n, err
+}
+
+func (d *decoder) begin(section string) {
+	d.section = section
+	d.function = -1
+	d.mark()
+}
+
+func (d *decoder) mark() {
+	d.start = d.offset
+}
+
+func (d *decoder) fail(err error) *LoadError {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return &LoadError{d.section, d.function, d.start, err}
+}
+
+func (d *decoder) read(data any) error {
+	return binary.Read(d, binary.LittleEndian, data)
+}
+
+// readBytes reads size bytes in bounded steps, so that a lying length
+// fails at the end of input instead of allocating it up front.
+func (d *decoder) readBytes(size int) ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := io.CopyN(&buf, d, int64(size))
+	return buf.Bytes(), err
+}
+
+func (d *decoder) readInstructions() ([]Instruction, error) {
+	instructions := make([]Instruction, 0)
+	var byte_buffer uint8
 	for {
-		err = binary.Read(file, binary.LittleEndian, &byte_buffer)
+		d.mark()
+		err := d.read(&byte_buffer)
 		if err != nil {
 			return nil, err
 		}
 		if byte_buffer == SEPRATOR {
-			break
+			return instructions, nil
+		}
+		if len(instructions) >= MaxInstructions {
+			return nil, fmt.Errorf("more than %d instructions", MaxInstructions)
 		}
 
-		inst, err := readInstruction(byte_buffer, file)
+		inst, err := readInstruction(byte_buffer, d)
 		if err != nil {
 			return nil, err
 		}
-		compiledFile.Chunk = append(compiledFile.Chunk, inst)
+		instructions = append(instructions, inst)
 	}
+}
 
-	// Read Functions
-	for {
-		err = binary.Read(file, binary.LittleEndian, &byte_buffer)
+// readDebug reads the debug section, which follows the functions after a
+// SEPRATOR: a uint16 count of uint16-length file names, then for the main
+// chunk and each function a uint32 count of entries, each made of a
+// uint32 instruction index, a uint16 file index and uint32 line and column.
+func (d *decoder) readDebug(functions int) (*DebugInfo, error) {
+	debug := new(DebugInfo)
+
+	var file_count uint16
+	err := d.read(&file_count)
+	if err != nil {
+		return nil, err
+	}
+	debug.Files = make([]string, 0, file_count)
+	for i := 0; i < int(file_count); i++ {
+		d.mark()
+		var name_size uint16
+		err = d.read(&name_size)
 		if err != nil {
-			if err == io.EOF {
-				return compiledFile, nil
-			}
 			return nil, err
 		}
+		name, err := d.readBytes(int(name_size))
+		if err != nil {
+			return nil, err
+		}
+		debug.Files = append(debug.Files, string(name))
+	}
 
-		param_size := int32(byte_buffer)
-		function := new(FunctionChunk)
-		function.Params = make([]uint16, param_size)
-		err = binary.Read(file, binary.LittleEndian, &function.Params)
+	debug.Functions = make([][]LineEntry, functions)
+	for block := -1; block < functions; block++ {
+		d.mark()
+		var entry_count uint32
+		err = d.read(&entry_count)
 		if err != nil {
 			return nil, err
 		}
-		function.Instructions = make([]Instruction, 0)
-		for {
-			err = binary.Read(file, binary.LittleEndian, &byte_buffer)
+		if entry_count > MaxInstructions {
+			return nil, fmt.Errorf("more than %d line entries", MaxInstructions)
+		}
+		entries := make([]LineEntry, 0)
+		for i := 0; i < int(entry_count); i++ {
+			d.mark()
+			var raw lineRecord
+			err = d.read(&raw)
 			if err != nil {
 				return nil, err
 			}
-			if byte_buffer == SEPRATOR {
-				break
+			entry := LineEntry{int(raw.Index), int(raw.File), int(raw.Line), int(raw.Column)}
+			if len(entries) > 0 && entries[len(entries)-1].Index >= entry.Index {
+				return nil, fmt.Errorf("line entries are not sorted")
 			}
-
-			inst, err := readInstruction(byte_buffer, file)
-			if err != nil {
-				return nil, err
-			}
-			function.Instructions = append(function.Instructions, inst)
+			entries = append(entries, entry)
+		}
+		if block < 0 {
+			debug.Chunk = entries
+		} else {
+			debug.Functions[block] = entries
 		}
-		compiledFile.Functions = append(compiledFile.Functions, function)
 	}
+
+	d.mark()
+	var trailing uint8
+	err = d.read(&trailing)
+	if err == nil {
+		return nil, fmt.Errorf("unexpected data after debug section")
+	} else if err != io.EOF {
+		return nil, err
+	}
+	return debug, nil
 }
 
 func codeSize(op OpCode) int {
 	switch op {
 	case LOADC, LOAD, DECL, ASGN:
 		return 16
-	case JMP, JMPT, JMPF:
+	case JMP, JMPT, JMPF, TRY:
 		return 16
 	case FUNC, PRINT, CALL, CALWT:
 		return 8
@@ -184,7 +374,13 @@ func codeSize(op OpCode) int {
 	}
 }
 
-func readInstruction(byte_buffer byte, file *os.File) (Instruction, error) {
+// fitsOperand reports whether val can be encoded as the operand of op.
+func fitsOperand(op OpCode, val int) bool {
+	size := codeSize(op)
+	return size == 0 || val >= 0 && val < 1<<size
+}
+
+func readInstruction(byte_buffer byte, file io.Reader) (Instruction, error) {
 	var err error
 	inst := Instruction{}
 	inst.Op = OpCode(byte_buffer)
@@ -0,0 +1,112 @@
+package vm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var fuzzSeeds = []string{`
+.symbols
+	"x"
+.literals
+	1
+	"s"
+	NONE
+	BOOL
+.main
+	LOADC 3
+	DECL 0
+	POP
+`, `
+.symbols
+	"f"
+	"a"
+.literals
+	2
+.file "main.kula"
+.main
+.loc "main.kula" 1 1
+	FUNC 0
+	DECL 0
+	POP
+	LOAD 0
+	LOADC 3
+	CALL 1
+	PRINT 1
+.func 1
+.loc "main.kula" 2 3
+	TRY handler
+	LOAD 1
+	THROW
+handler:	RETV
+`}
+
+func FuzzLoad(f *testing.F) {
+	for _, src := range fuzzSeeds {
+		cf, err := Assemble(strings.NewReader(src))
+		if err != nil {
+			f.Fatal(err)
+		}
+		var buf bytes.Buffer
+		_, err = cf.WriteTo(&buf)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(buf.Bytes())
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		cf, err := LoadBytes(data)
+		if err != nil {
+			return
+		}
+		var first bytes.Buffer
+		_, err = cf.WriteTo(&first)
+		if err != nil {
+			t.Fatalf("write loaded file: %v", err)
+		}
+		if !bytes.Equal(data, first.Bytes()) {
+			t.Fatalf("writing the loaded file changed its bytes")
+		}
+		reloaded, err := LoadBytes(first.Bytes())
+		if err != nil {
+			t.Fatalf("reload written file: %v", err)
+		}
+		var second bytes.Buffer
+		_, err = reloaded.WriteTo(&second)
+		if err != nil {
+			t.Fatalf("write reloaded file: %v", err)
+		}
+		if !bytes.Equal(first.Bytes(), second.Bytes()) {
+			t.Fatalf("round trip changed the file")
+		}
+		Verify(cf)
+	})
+}
+
+func TestOperandRange(t *testing.T) {
+	var src strings.Builder
+	src.WriteString(".main\n\tJMP far\n")
+	for i := 0; i < 1<<16; i++ {
+		src.WriteString("\tDUP\n")
+	}
+	src.WriteString("far:\tDUP\n")
+	_, err := Assemble(strings.NewReader(src.String()))
+	if err == nil || !strings.Contains(err.Error(), "out of jump range") {
+		t.Errorf("assemble: error = %v, want a jump range error", err)
+	}
+
+	cf, err := Assemble(strings.NewReader(".main\n\tPRINT 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf.Chunk[0].Val = 256
+	if _, err := cf.WriteTo(new(bytes.Buffer)); err == nil || !strings.Contains(err.Error(), "does not fit in 8 bits") {
+		t.Errorf("write: error = %v, want an operand width error", err)
+	}
+	errs := Verify(cf)
+	if len(errs) == 0 || !strings.Contains(errs[0].Error(), "does not fit in 8 bits") {
+		t.Errorf("verify: errors = %v, want an operand width error", errs)
+	}
+}
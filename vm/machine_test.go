@@ -0,0 +1,101 @@
+package vm
+
+import (
+	"fmt"
+	"gokula/objects"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// sumProgram assembles a file that pushes 0..n-1 onto an array and sums
+// them into total, touching globals, prototypes and the quota counters.
+func sumProgram(t *testing.T, n int) *CompiledFile {
+	t.Helper()
+	src := fmt.Sprintf(`
+.symbols
+	"total"
+	"i"
+	"arr"
+	"Array"
+.literals
+	0
+	1
+	%d
+	"push"
+.main
+	LOADC 3
+	DECL 0
+	POP
+	LOADC 3
+	DECL 1
+	POP
+	LOAD 3
+	CALL 0
+	DECL 2
+	POP
+loop:	LOAD 1
+	LOADC 5
+	LT
+	JMPF done
+	LOAD 2
+	LOADC 6
+	GETWT
+	LOAD 1
+	CALWT 1
+	POP
+	LOAD 0
+	LOAD 1
+	ADD
+	ASGN 0
+	POP
+	LOAD 1
+	LOADC 4
+	ADD
+	ASGN 1
+	POP
+	JMP loop
+done:
+`, n)
+	cf, err := Assemble(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cf
+}
+
+func TestConcurrentMachines(t *testing.T) {
+	const files, machines = 5, 10
+	compiled := make([]*CompiledFile, files)
+	for i := range compiled {
+		compiled[i] = sumProgram(t, 100*(i+1))
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, files*machines)
+	for i := 0; i < files*machines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			n := 100 * (i%files + 1)
+			m := NewMachine(compiled[i%files], WithMaxArrayElements(int64(n)))
+			if err := m.Run(); err != nil {
+				errs <- err
+				return
+			}
+			total, _ := m.Global().Get("total")
+			if want := objects.FromInt(n * (n - 1) / 2); total != want {
+				errs <- fmt.Errorf("machine %d: total = %v, want %v", i, total, want)
+			}
+			arr, _ := m.Global().Get("arr")
+			if length := arr.(*objects.KulaArray).Length(); length != objects.FromInt(n) {
+				errs <- fmt.Errorf("machine %d: length = %v, want %d", i, length, n)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,281 @@
+package vm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"gokula/objects"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"time"
+)
+
+// Capability names a module of natives that touches the outside world.
+type Capability string
+
+const (
+	CapTime   Capability = "time"
+	CapIO     Capability = "io"
+	CapOS     Capability = "os"
+	CapEnv    Capability = "env"
+	CapRandom Capability = "random"
+)
+
+var (
+	allCapabilities = []Capability{CapTime, CapIO, CapOS, CapEnv, CapRandom}
+	// Only the clock was available before capabilities existed, so nothing
+	// that reaches files, the environment or the process is granted by default.
+	defaultCapabilities = []Capability{CapTime}
+)
+
+// AllCapabilities returns every capability a machine can grant.
+func AllCapabilities() []Capability {
+	return append([]Capability(nil), allCapabilities...)
+}
+
+// DefaultCapabilities returns the capabilities granted when
+// WithCapabilities is not given.
+func DefaultCapabilities() []Capability {
+	return append([]Capability(nil), defaultCapabilities...)
+}
+
+var ErrCapabilityDenied = errors.New("capability denied")
+
+func ParseCapability(name string) (Capability, error) {
+	for _, c := range allCapabilities {
+		if string(c) == name {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("unknown capability '%s'", name)
+}
+
+// WithCapabilities installs only the natives of the given capabilities.
+// Natives of the others are replaced by stubs failing with
+// ErrCapabilityDenied. Without this option DefaultCapabilities are allowed.
+func WithCapabilities(caps ...Capability) Option {
+	return func(m *Machine) {
+		m.capabilities = make(map[Capability]bool)
+		for _, c := range caps {
+			m.capabilities[c] = true
+		}
+	}
+}
+
+// WithArgs sets the script arguments returned by args() of the os module.
+func WithArgs(args ...string) Option {
+	return func(m *Machine) {
+		m.args = args
+	}
+}
+
+func (m *Machine) allowed(c Capability) bool {
+	if m.capabilities == nil {
+		for _, d := range defaultCapabilities {
+			if d == c {
+				return true
+			}
+		}
+		return false
+	}
+	return m.capabilities[c]
+}
+
+func (m *Machine) initModules() {
+	for c, natives := range m.modules() {
+		for name, native := range natives {
+			if !m.allowed(c) {
+				native = deniedFunction(name, c)
+			}
+			m.global.Define(name, native)
+		}
+	}
+}
+
+func deniedFunction(name string, c Capability) *NativeFunction {
+	return NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			return nil, fmt.Errorf("%w: '%s' needs capability '%s'", ErrCapabilityDenied, name, c)
+		}, -1,
+	)
+}
+
+func (m *Machine) newString(s string) (*objects.KulaString, error) {
+	err := m.alloc(quotaStringBytes, len(s))
+	if err != nil {
+		return nil, err
+	}
+	str := objects.KulaString(s)
+	return &str, nil
+}
+
+func (m *Machine) modules() map[Capability]map[string]*NativeFunction {
+	startTime := time.Now().UnixNano()
+	var stdin *bufio.Reader
+	var random *rand.Rand
+
+	return map[Capability]map[string]*NativeFunction{
+		CapTime: {
+			"clock": NewNativeFunction(
+				func(this any, argv []any) (any, error) {
+					return objects.KulaNumber(float64(time.Now().UnixNano()-startTime) / 1000000000.0), nil
+				}, 0,
+			),
+			"now": NewNativeFunction(
+				func(this any, argv []any) (any, error) {
+					return objects.KulaNumber(float64(time.Now().UnixNano()) / 1000000000.0), nil
+				}, 0,
+			),
+			"sleep": NewNativeFunction(
+				func(this any, argv []any) (any, error) {
+					seconds, err := assert[objects.KulaNumber](argv[0])
+					if err != nil {
+						return nil, err
+					}
+					if !(seconds >= 0) {
+						return nil, fmt.Errorf("sleep needs a non-negative number of seconds")
+					}
+					d := time.Duration(math.MaxInt64)
+					if float64(seconds) < float64(math.MaxInt64)/float64(time.Second) {
+						d = time.Duration(float64(seconds) * float64(time.Second))
+					}
+					return nil, m.wait(d)
+				}, 1,
+			),
+		},
+		CapIO: {
+			"input": NewNativeFunction(
+				func(this any, argv []any) (any, error) {
+					if stdin == nil {
+						stdin = bufio.NewReader(os.Stdin)
+					}
+					line, err := stdin.ReadString('\n')
+					if err != nil && line == "" {
+						return nil, nil
+					}
+					return m.newString(trimNewline(line))
+				}, 0,
+			),
+			"readFile": NewNativeFunction(
+				func(this any, argv []any) (any, error) {
+					path, err := assert[*objects.KulaString](argv[0])
+					if err != nil {
+						return nil, err
+					}
+					data, err := os.ReadFile(string(*path))
+					if err != nil {
+						return nil, err
+					}
+					return m.newString(string(data))
+				}, 1,
+			),
+			"writeFile": NewNativeFunction(
+				func(this any, argv []any) (any, error) {
+					path, err := assert[*objects.KulaString](argv[0])
+					if err != nil {
+						return nil, err
+					}
+					return nil, os.WriteFile(string(*path), []byte(*objects.Stringify(argv[1])), 0644)
+				}, 2,
+			),
+		},
+		CapOS: {
+			"args": NewNativeFunction(
+				func(this any, argv []any) (any, error) {
+					err := m.alloc(quotaArrayElements, len(m.args))
+					if err != nil {
+						return nil, err
+					}
+					arr := objects.NewArray()
+					for _, arg := range m.args {
+						str := objects.KulaString(arg)
+						arr.Insert(arr.Length(), &str)
+					}
+					return arr, nil
+				}, 0,
+			),
+			"platform": NewNativeFunction(
+				func(this any, argv []any) (any, error) {
+					return m.newString(runtime.GOOS)
+				}, 0,
+			),
+			"cwd": NewNativeFunction(
+				func(this any, argv []any) (any, error) {
+					dir, err := os.Getwd()
+					if err != nil {
+						return nil, err
+					}
+					return m.newString(dir)
+				}, 0,
+			),
+		},
+		CapEnv: {
+			"getenv": NewNativeFunction(
+				func(this any, argv []any) (any, error) {
+					name, err := assert[*objects.KulaString](argv[0])
+					if err != nil {
+						return nil, err
+					}
+					value, ok := os.LookupEnv(string(*name))
+					if !ok {
+						return nil, nil
+					}
+					return m.newString(value)
+				}, 1,
+			),
+		},
+		CapRandom: {
+			"random": NewNativeFunction(
+				func(this any, argv []any) (any, error) {
+					if random == nil {
+						random = rand.New(rand.NewSource(time.Now().UnixNano()))
+					}
+					return objects.FromFloat64(random.Float64()), nil
+				}, 0,
+			),
+			"randint": NewNativeFunction(
+				func(this any, argv []any) (any, error) {
+					low, err := assert[objects.KulaNumber](argv[0])
+					if err != nil {
+						return nil, err
+					}
+					high, err := assert[objects.KulaNumber](argv[1])
+					if err != nil {
+						return nil, err
+					}
+					if !isSafeInteger(low) || !isSafeInteger(high) {
+						return nil, fmt.Errorf("randint bounds must be integers between -2^53 and 2^53")
+					}
+					if high < low {
+						return nil, fmt.Errorf("randint needs low <= high")
+					}
+					if random == nil {
+						random = rand.New(rand.NewSource(time.Now().UnixNano()))
+					}
+					span := int64(high) - int64(low) + 1
+					return objects.KulaNumber(float64(int64(low) + random.Int63n(span))), nil
+				}, 2,
+			),
+		},
+	}
+}
+
+// maxSafeInteger is the largest integer up to which every integer is a
+// representable KulaNumber.
+const maxSafeInteger = 1 << 53
+
+func isSafeInteger(n objects.KulaNumber) bool {
+	return n == objects.KulaNumber(math.Trunc(float64(n))) && n >= -maxSafeInteger && n <= maxSafeInteger
+}
+
+func trimNewline(line string) string {
+	if len(line) > 0 && line[len(line)-1] == '\n' {
+		line = line[:len(line)-1]
+	}
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+	return line
+}
@@ -0,0 +1,147 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrBudgetExceeded = errors.New("execution budget exceeded")
+	ErrCanceled       = errors.New("execution canceled")
+	ErrQuotaExceeded  = errors.New("quota exceeded")
+)
+
+const (
+	quotaArrayElements = iota
+	quotaObjectKeys
+	quotaStringBytes
+	quotaKinds
+)
+
+var quotaNames = [quotaKinds]string{"array elements", "object keys", "string bytes"}
+
+// checkInterval is how many instructions run between deadline and
+// cancellation checks.
+const checkInterval = 1024
+
+type Option func(m *Machine)
+
+// WithMaxInstructions stops Run with ErrBudgetExceeded after n instructions.
+func WithMaxInstructions(n int64) Option {
+	return func(m *Machine) {
+		m.maxInstructions = n
+	}
+}
+
+// WithDeadline stops Run with ErrBudgetExceeded once t has passed.
+func WithDeadline(t time.Time) Option {
+	return func(m *Machine) {
+		m.deadline = t
+	}
+}
+
+// WithContext stops Run with ErrCanceled once ctx is done.
+func WithContext(ctx context.Context) Option {
+	return func(m *Machine) {
+		m.ctx = ctx
+	}
+}
+
+// WithMaxCallDepth limits how deep Kula functions may recurse.
+func WithMaxCallDepth(n int) Option {
+	return func(m *Machine) {
+		m.maxCallDepth = n
+	}
+}
+
+// WithMaxStackSize limits the operand stack of every frame.
+func WithMaxStackSize(n int) Option {
+	return func(m *Machine) {
+		m.maxStackSize = n
+	}
+}
+
+// WithMaxArrayElements limits the array elements allocated during a run.
+func WithMaxArrayElements(n int64) Option {
+	return func(m *Machine) {
+		m.quotaLimits[quotaArrayElements] = n
+	}
+}
+
+// WithMaxObjectKeys limits the object keys allocated during a run.
+func WithMaxObjectKeys(n int64) Option {
+	return func(m *Machine) {
+		m.quotaLimits[quotaObjectKeys] = n
+	}
+}
+
+// WithMaxStringBytes limits the string bytes allocated during a run.
+func WithMaxStringBytes(n int64) Option {
+	return func(m *Machine) {
+		m.quotaLimits[quotaStringBytes] = n
+	}
+}
+
+// alloc accounts n units of kind against its quota before they are allocated.
+func (m *Machine) alloc(kind int, n int) error {
+	limit := m.quotaLimits[kind]
+	if limit > 0 && m.quotaUsed[kind]+int64(n) > limit {
+		return fmt.Errorf("%w: more than %d %s", ErrQuotaExceeded, limit, quotaNames[kind])
+	}
+	m.quotaUsed[kind] += int64(n)
+	return nil
+}
+
+// checkBudget is called by the dispatch loop before every instruction.
+func (m *Machine) checkBudget() error {
+	m.steps++
+	if m.maxInstructions > 0 && m.steps > m.maxInstructions {
+		return fmt.Errorf("%w: more than %d instructions", ErrBudgetExceeded, m.maxInstructions)
+	}
+	if m.steps%checkInterval != 0 {
+		return nil
+	}
+	if !m.deadline.IsZero() && time.Now().After(m.deadline) {
+		return fmt.Errorf("%w: deadline %s passed", ErrBudgetExceeded, m.deadline.Format(time.RFC3339))
+	}
+	if m.ctx != nil {
+		select {
+		case <-m.ctx.Done():
+			return fmt.Errorf("%w: %w", ErrCanceled, m.ctx.Err())
+		default:
+		}
+	}
+	return nil
+}
+
+// wait blocks for d, returning early like checkBudget when the deadline
+// passes or the context is canceled.
+func (m *Machine) wait(d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	var deadline <-chan time.Time
+	if !m.deadline.IsZero() {
+		deadlineTimer := time.NewTimer(time.Until(m.deadline))
+		defer deadlineTimer.Stop()
+		deadline = deadlineTimer.C
+	}
+	var done <-chan struct{}
+	if m.ctx != nil {
+		done = m.ctx.Done()
+	}
+	select {
+	case <-timer.C:
+		return nil
+	case <-deadline:
+		return fmt.Errorf("%w: deadline %s passed", ErrBudgetExceeded, m.deadline.Format(time.RFC3339))
+	case <-done:
+		return fmt.Errorf("%w: %w", ErrCanceled, m.ctx.Err())
+	}
+}
+
+// catchable reports whether a script may handle err with TRY.
+func catchable(err error) bool {
+	return !errors.Is(err, ErrBudgetExceeded) && !errors.Is(err, ErrCanceled) && !errors.Is(err, ErrQuotaExceeded)
+}
@@ -3,17 +3,40 @@ package vm
 import (
 	"fmt"
 	"gokula/objects"
-	"time"
 )
 
 func assert[T any](v any) (zero T, err error) {
 	if val, ok := v.(T); ok {
 		return val, nil
 	}
-	err = fmt.Errorf("wrong argument '%s' type", *objects.Stringify(v))
+	err = fmt.Errorf("wrong argument '%s' type, expect '%s' but '%s' given", *objects.Stringify(v), typeName[T](), *TypeOf(v))
 	return
 }
 
+// typeName names T the way TypeOf names its values.
+func typeName[T any]() string {
+	var zero T
+	switch any(zero).(type) {
+	case objects.KulaBool:
+		return "Bool"
+	case objects.KulaNumber:
+		return "Number"
+	case *objects.KulaString:
+		return "String"
+	case *objects.KulaArray:
+		return "Array"
+	case *objects.KulaObject:
+		return "Object"
+	case *objects.KulaMap:
+		return "Map"
+	case *objects.KulaSet:
+		return "Set"
+	case *VMFunction, *NativeFunction:
+		return "Function"
+	}
+	return fmt.Sprintf("%T", zero)
+}
+
 func TypeOf(val any) *objects.KulaString {
 	var str objects.KulaString
 	if val == nil {
@@ -28,6 +51,10 @@ func TypeOf(val any) *objects.KulaString {
 		str = "Array"
 	} else if _, ok := val.(*objects.KulaObject); ok {
 		str = "Object"
+	} else if _, ok := val.(*objects.KulaMap); ok {
+		str = "Map"
+	} else if _, ok := val.(*objects.KulaSet); ok {
+		str = "Set"
 	} else if _, ok := val.(*VMFunction); ok {
 		str = "Function"
 	} else if _, ok := val.(*NativeFunction); ok {
@@ -36,156 +63,163 @@ func TypeOf(val any) *objects.KulaString {
 	return &str
 }
 
-func initStdlib() error {
-	startTime := time.Now().UnixNano()
-	global.Define("clock", NewNativeFunction(
+func (m *Machine) initStdlib() error {
+	m.global.Define("String", NewNativeFunction(
 		func(this any, argv []any) (any, error) {
-			return objects.KulaNumber(float64(time.Now().UnixNano()-startTime) / 1000000000.0), nil
-		}, 0,
-	))
-	global.Define("String", NewNativeFunction(
-		func(this any, argv []any) (any, error) {
-			return objects.Stringify(argv[0]), nil
+			str := objects.Stringify(argv[0])
+			err := m.alloc(quotaStringBytes, len(*str))
+			if err != nil {
+				return nil, err
+			}
+			return str, nil
 		}, 1,
 	))
-	global.Define("Bool", NewNativeFunction(
+	m.global.Define("Bool", NewNativeFunction(
 		func(this any, argv []any) (any, error) {
 			return objects.Booleanify(argv[0]), nil
 		}, 1,
 	))
-	global.Define("Object", NewNativeFunction(
+	m.global.Define("Object", NewNativeFunction(
 		func(this any, argv []any) (any, error) {
 			return objects.NewObject(), nil
 		}, 0,
 	))
-	global.Define("Array", NewNativeFunction(
+	m.global.Define("Array", NewNativeFunction(
 		func(this any, argv []any) (any, error) {
 			return objects.NewArray(), nil
 		}, 0,
 	))
-	global.Define("asArray", NewNativeFunction(
+	m.global.Define("asArray", NewNativeFunction(
 		func(this any, argv []any) (any, error) {
+			err := m.alloc(quotaArrayElements, len(argv))
+			if err != nil {
+				return nil, err
+			}
 			return objects.FromSlice(argv), nil
 		}, -1,
 	))
-	global.Define("asObject", NewNativeFunction(
+	m.global.Define("asObject", NewNativeFunction(
 		func(this any, argv []any) (any, error) {
 			if len(argv)%2 == 1 {
 				return nil, fmt.Errorf("need odd arguments but even is given")
 			}
+			err := m.alloc(quotaObjectKeys, len(argv)/2)
+			if err != nil {
+				return nil, err
+			}
 			obj := objects.NewObject()
 			for i := 0; i+1 < len(argv); i += 2 {
-				key := argv[i].(*objects.KulaString)
+				key, err := assert[*objects.KulaString](argv[i])
+				if err != nil {
+					return nil, err
+				}
 				obj.Set(key, argv[i+1])
 			}
 			return obj, nil
 		}, -1,
 	))
-	global.Define("typeof", NewNativeFunction(
+	m.global.Define("Error", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			message, err := assert[*objects.KulaString](argv[0])
+			if err != nil {
+				return nil, err
+			}
+			return m.newError(string(*message)), nil
+		}, 1,
+	))
+	m.global.Define("typeof", NewNativeFunction(
 		func(this any, argv []any) (any, error) {
 			return TypeOf(this), nil
 		}, 1,
 	))
-	global.Define("__string_proto__", objects.StringProto)
-	global.Define("__array_proto__", objects.ArrayProto)
-	global.Define("__number_proto__", objects.NumberProto)
-	global.Define("__object_proto__", objects.ObjectProto)
-	global.Define("__string_proto__", objects.StringProto)
+	m.global.Define("__string_proto__", m.stringProto)
+	m.global.Define("__array_proto__", m.arrayProto)
+	m.global.Define("__number_proto__", m.numberProto)
+	m.global.Define("__object_proto__", m.objectProto)
+	m.global.Define("__bool_proto__", m.boolProto)
+	m.global.Define("__error_proto__", m.errorProto)
+	m.global.Define("__map_proto__", m.mapProto)
+	m.global.Define("__set_proto__", m.setProto)
 
-	objects.StringProto.SetNative("at", NewNativeFunction(
+	m.initStringProto()
+	m.initArrayProto()
+	m.initMath()
+	m.initJSON()
+	m.initCollections()
+	m.initModules()
+	m.global.Define("__string_proto__", m.stringProto)
+
+	m.objectProto.SetNative("copy", NewNativeFunction(
 		func(this any, argv []any) (any, error) {
-			str, err := assert[objects.KulaString](this)
+			obj, err := This[*objects.KulaObject](this)
 			if err != nil {
 				return nil, err
 			}
-			index, err := assert[objects.KulaNumber](argv[0])
+			err = m.alloc(quotaObjectKeys, obj.Len())
 			if err != nil {
 				return nil, err
 			}
-			return str.At(index), nil
-		}, 1,
+			copied := objects.NewObject()
+			for _, k := range obj.Keys() {
+				v, _ := obj.Own(k)
+				copied.SetNative(k, v)
+			}
+			return copied, nil
+		}, 0,
 	))
-	objects.StringProto.SetNative("cut", NewNativeFunction(
+	m.objectProto.SetNative("keys", NewNativeFunction(
 		func(this any, argv []any) (any, error) {
-			str, err := assert[objects.KulaString](this)
+			obj, err := This[*objects.KulaObject](this)
 			if err != nil {
 				return nil, err
 			}
-			index, err := assert[objects.KulaNumber](argv[0])
+			err = m.alloc(quotaArrayElements, obj.Len())
 			if err != nil {
 				return nil, err
 			}
-			len, err := assert[objects.KulaNumber](argv[1])
-			if err != nil {
-				return nil, err
+			arr := objects.NewArray()
+			for _, k := range obj.Keys() {
+				key := objects.KulaString(k)
+				arr.Insert(arr.Length(), &key)
 			}
-			return str.Cut(index, len), nil
-		}, 2,
+			return arr, nil
+		}, 0,
 	))
-
-	objects.ArrayProto.SetNative("insert", NewNativeFunction(
+	m.objectProto.SetNative("values", NewNativeFunction(
 		func(this any, argv []any) (any, error) {
-			arr := this.(*objects.KulaArray)
-			index, err := assert[objects.KulaNumber](argv[0])
+			obj, err := This[*objects.KulaObject](this)
 			if err != nil {
 				return nil, err
 			}
-			value := argv[1]
-			arr.Insert(index, value)
-			return nil, nil
-		}, 2,
-	))
-	objects.ArrayProto.SetNative("remove", NewNativeFunction(
-		func(this any, argv []any) (any, error) {
-			arr := this.(*objects.KulaArray)
-			index, err := assert[objects.KulaNumber](argv[0])
+			err = m.alloc(quotaArrayElements, obj.Len())
 			if err != nil {
 				return nil, err
 			}
-			arr.Remove(index)
-			return nil, nil
-		}, 2,
-	))
-
-	objects.ObjectProto.SetNative("copy", NewNativeFunction(
-		func(this any, argv []any) (any, error) {
-			obj := this.(*objects.KulaObject)
-			copied := objects.NewObject()
-			for k, v := range *obj {
-				copied.Set((*objects.KulaString)(&k), v)
-			}
-			return copied, nil
-		}, 0,
-	))
-	objects.ObjectProto.SetNative("keys", NewNativeFunction(
-		func(this any, argv []any) (any, error) {
-			obj := this.(*objects.KulaObject)
-			arr := objects.NewArray()
-			for k := range *obj {
-				arr.Insert(arr.Length(), (*objects.KulaString)(&k))
-			}
-			return arr, nil
-		}, 0,
-	))
-	objects.ObjectProto.SetNative("values", NewNativeFunction(
-		func(this any, argv []any) (any, error) {
-			obj := this.(*objects.KulaObject)
 			arr := objects.NewArray()
-			for _, v := range *obj {
+			for _, k := range obj.Keys() {
+				v, _ := obj.Own(k)
 				arr.Insert(arr.Length(), v)
 			}
 			return arr, nil
 		}, 0,
 	))
 
-	objects.NumberProto.SetNative("floor", NewNativeFunction(
+	m.numberProto.SetNative("floor", NewNativeFunction(
 		func(this any, argv []any) (any, error) {
-			return this.(objects.KulaNumber).Floor(), nil
+			n, err := This[objects.KulaNumber](this)
+			if err != nil {
+				return nil, err
+			}
+			return n.Floor(), nil
 		}, 0,
 	))
-	objects.NumberProto.SetNative("round", NewNativeFunction(
+	m.numberProto.SetNative("round", NewNativeFunction(
 		func(this any, argv []any) (any, error) {
-			return this.(objects.KulaNumber).Round(), nil
+			n, err := This[objects.KulaNumber](this)
+			if err != nil {
+				return nil, err
+			}
+			return n.Round(), nil
 		}, 0,
 	))
 
@@ -0,0 +1,250 @@
+package vm
+
+import (
+	"gokula/objects"
+)
+
+// callback adapts a Kula function to an objects.ArrayCallback.
+func (m *Machine) callback(fn any) objects.ArrayCallback {
+	return func(item any, index int) (any, error) {
+		return m.Call(fn, nil, item, objects.FromInt(index))
+	}
+}
+
+func (m *Machine) initArrayProto() {
+	m.arrayProto.SetNative("length", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			arr, err := This[*objects.KulaArray](this)
+			if err != nil {
+				return nil, err
+			}
+			return arr.Length(), nil
+		}, 0,
+	))
+	m.arrayProto.SetNative("insert", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			arr, err := This[*objects.KulaArray](this)
+			if err != nil {
+				return nil, err
+			}
+			index, err := assert[objects.KulaNumber](argv[0])
+			if err != nil {
+				return nil, err
+			}
+			value := argv[1]
+			err = m.alloc(quotaArrayElements, 1)
+			if err != nil {
+				return nil, err
+			}
+			arr.Insert(index, value)
+			return nil, nil
+		}, 2,
+	))
+	m.arrayProto.SetNative("remove", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			arr, err := This[*objects.KulaArray](this)
+			if err != nil {
+				return nil, err
+			}
+			index, err := assert[objects.KulaNumber](argv[0])
+			if err != nil {
+				return nil, err
+			}
+			arr.Remove(index)
+			return nil, nil
+		}, 1,
+	))
+	m.arrayProto.SetNative("push", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			arr, err := This[*objects.KulaArray](this)
+			if err != nil {
+				return nil, err
+			}
+			err = m.alloc(quotaArrayElements, len(argv))
+			if err != nil {
+				return nil, err
+			}
+			arr.Push(argv...)
+			return arr.Length(), nil
+		}, -1,
+	))
+	m.arrayProto.SetNative("pop", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			arr, err := This[*objects.KulaArray](this)
+			if err != nil {
+				return nil, err
+			}
+			return arr.Pop(), nil
+		}, 0,
+	))
+	m.arrayProto.SetNative("slice", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			arr, err := This[*objects.KulaArray](this)
+			if err != nil {
+				return nil, err
+			}
+			start, err := OptArg(argv, 0, objects.KulaNumber(0))
+			if err != nil {
+				return nil, err
+			}
+			end, err := OptArg(argv, 1, arr.Length())
+			if err != nil {
+				return nil, err
+			}
+			slice := arr.Slice(start, end)
+			return slice, m.alloc(quotaArrayElements, len(*slice))
+		}, 0,
+	))
+	m.arrayProto.SetNative("concat", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			arr, err := This[*objects.KulaArray](this)
+			if err != nil {
+				return nil, err
+			}
+			others := make([]*objects.KulaArray, len(argv))
+			size := len(*arr)
+			for i := range argv {
+				others[i], err = Arg[*objects.KulaArray](argv, i)
+				if err != nil {
+					return nil, err
+				}
+				size += len(*others[i])
+			}
+			err = m.alloc(quotaArrayElements, size)
+			if err != nil {
+				return nil, err
+			}
+			return arr.Concat(others...), nil
+		}, -1,
+	))
+	m.arrayProto.SetNative("join", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			arr, err := This[*objects.KulaArray](this)
+			if err != nil {
+				return nil, err
+			}
+			comma := objects.KulaString(",")
+			seprator, err := OptArg(argv, 0, &comma)
+			if err != nil {
+				return nil, err
+			}
+			return m.newString(arr.Join(string(*seprator)))
+		}, 0,
+	))
+	m.arrayProto.SetNative("indexOf", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			arr, err := This[*objects.KulaArray](this)
+			if err != nil {
+				return nil, err
+			}
+			return arr.IndexOf(argv[0]), nil
+		}, 1,
+	))
+	m.arrayProto.SetNative("reverse", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			arr, err := This[*objects.KulaArray](this)
+			if err != nil {
+				return nil, err
+			}
+			arr.Reverse()
+			return arr, nil
+		}, 0,
+	))
+	m.arrayProto.SetNative("forEach", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			arr, err := This[*objects.KulaArray](this)
+			if err != nil {
+				return nil, err
+			}
+			return nil, arr.ForEach(m.callback(argv[0]))
+		}, 1,
+	))
+	m.arrayProto.SetNative("map", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			arr, err := This[*objects.KulaArray](this)
+			if err != nil {
+				return nil, err
+			}
+			err = m.alloc(quotaArrayElements, len(*arr))
+			if err != nil {
+				return nil, err
+			}
+			return arr.Map(m.callback(argv[0]))
+		}, 1,
+	))
+	m.arrayProto.SetNative("filter", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			arr, err := This[*objects.KulaArray](this)
+			if err != nil {
+				return nil, err
+			}
+			err = m.alloc(quotaArrayElements, len(*arr))
+			if err != nil {
+				return nil, err
+			}
+			return arr.Filter(m.callback(argv[0]))
+		}, 1,
+	))
+	m.arrayProto.SetNative("reduce", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			arr, err := This[*objects.KulaArray](this)
+			if err != nil {
+				return nil, err
+			}
+			fn := argv[0]
+			return arr.Reduce(func(acc, item any, index int) (any, error) {
+				return m.Call(fn, nil, acc, item, objects.FromInt(index))
+			}, argv[1:]...)
+		}, 1,
+	))
+	m.arrayProto.SetNative("find", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			arr, err := This[*objects.KulaArray](this)
+			if err != nil {
+				return nil, err
+			}
+			return arr.Find(m.callback(argv[0]))
+		}, 1,
+	))
+	m.arrayProto.SetNative("some", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			arr, err := This[*objects.KulaArray](this)
+			if err != nil {
+				return nil, err
+			}
+			return arr.Some(m.callback(argv[0]))
+		}, 1,
+	))
+	m.arrayProto.SetNative("every", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			arr, err := This[*objects.KulaArray](this)
+			if err != nil {
+				return nil, err
+			}
+			return arr.Every(m.callback(argv[0]))
+		}, 1,
+	))
+	m.arrayProto.SetNative("sort", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			arr, err := This[*objects.KulaArray](this)
+			if err != nil {
+				return nil, err
+			}
+			less := objects.DefaultLess
+			if len(argv) > 0 && argv[0] != nil {
+				comparator := argv[0]
+				less = func(x, y any) (bool, error) {
+					result, err := m.Call(comparator, nil, x, y)
+					if err != nil {
+						return false, err
+					}
+					if n, ok := result.(objects.KulaNumber); ok {
+						return n < 0, nil
+					}
+					return bool(objects.Booleanify(result)), nil
+				}
+			}
+			return arr, arr.Sort(less)
+		}, 0,
+	))
+}
@@ -0,0 +1,217 @@
+package vm
+
+import (
+	"gokula/objects"
+	"strings"
+	"testing"
+)
+
+// callbackSource defines Kula functions used as callbacks by the tests:
+// byKey compares [key, tag] pairs, double, even and gt2 map single items,
+// add folds and failing throws "boom".
+const callbackSource = `
+.symbols
+	"byKey"
+	"a"
+	"b"
+	"double"
+	"x"
+	"even"
+	"add"
+	"acc"
+	"failing"
+	"gt2"
+.literals
+	0
+	2
+	"boom"
+.main
+	FUNC 0
+	DECL 0
+	POP
+	FUNC 1
+	DECL 3
+	POP
+	FUNC 2
+	DECL 5
+	POP
+	FUNC 3
+	DECL 6
+	POP
+	FUNC 4
+	DECL 8
+	POP
+	FUNC 5
+	DECL 9
+	POP
+.func 1 2
+	LOAD 1
+	LOADC 3
+	GET
+	LOAD 2
+	LOADC 3
+	GET
+	SUB
+	RETV
+.func 4
+	LOAD 4
+	LOAD 4
+	ADD
+	RETV
+.func 4
+	LOAD 4
+	LOADC 4
+	MOD
+	LOADC 3
+	EQ
+	RETV
+.func 7 4
+	LOAD 7
+	LOAD 4
+	ADD
+	RETV
+.func 1 2
+	LOADC 5
+	THROW
+.func 4
+	LOAD 4
+	LOADC 4
+	GT
+	RETV
+`
+
+// runSource assembles and runs src, returning the machine for inspection.
+func runSource(t *testing.T, src string) *Machine {
+	t.Helper()
+	cf, err := Assemble(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMachine(cf)
+	if err := m.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func numbers(ns ...float64) *objects.KulaArray {
+	arr := objects.NewArray()
+	for _, n := range ns {
+		arr.Push(objects.KulaNumber(n))
+	}
+	return arr
+}
+
+func str(s string) *objects.KulaString {
+	return (*objects.KulaString)(&s)
+}
+
+func TestArrayMethods(t *testing.T) {
+	m := runSource(t, callbackSource)
+	global := func(name string) any {
+		v, _ := m.Global().Get(name)
+		return v
+	}
+	pairs := func() *objects.KulaArray {
+		arr := objects.NewArray()
+		for i, key := range []float64{3, 1, 2, 1, 3, 1} {
+			arr.Push(objects.FromSlice([]any{objects.KulaNumber(key), objects.FromInt(i)}))
+		}
+		return arr
+	}
+
+	tests := []struct {
+		name   string
+		this   *objects.KulaArray
+		method string
+		args   []any
+		want   string // Stringify of the result
+		after  string // Stringify of the receiver afterwards, if checked
+		err    string // substring of the expected error
+	}{
+		{"push", numbers(1), "push", []any{objects.KulaNumber(2), objects.KulaNumber(3)}, "3", "[1,2,3]", ""},
+		{"pop", numbers(1, 2), "pop", nil, "2", "[1]", ""},
+		{"pop empty", numbers(), "pop", nil, "null", "[]", ""},
+		{"remove", numbers(1, 2, 3), "remove", []any{objects.KulaNumber(1)}, "null", "[1,3]", ""},
+		{"remove without index", numbers(1), "remove", nil, "", "", "expect 1 argument(s) but 0 given"},
+		{"slice", numbers(1, 2, 3, 4), "slice", []any{objects.KulaNumber(1), objects.KulaNumber(3)}, "[2,3]", "[1,2,3,4]", ""},
+		{"slice negative", numbers(1, 2, 3, 4), "slice", []any{objects.KulaNumber(-3), objects.KulaNumber(-1)}, "[2,3]", "", ""},
+		{"slice from negative", numbers(1, 2, 3, 4), "slice", []any{objects.KulaNumber(-2)}, "[3,4]", "", ""},
+		{"slice out of range", numbers(1, 2), "slice", []any{objects.KulaNumber(5)}, "[]", "", ""},
+		{"concat", numbers(1), "concat", []any{numbers(2), numbers(3, 4)}, "[1,2,3,4]", "[1]", ""},
+		{"concat non-array", numbers(1), "concat", []any{objects.KulaNumber(2)}, "", "", "argument 0"},
+		{"join", objects.FromSlice([]any{objects.KulaNumber(1), str("a"), nil}), "join", []any{str("-")}, "1-a-null", "", ""},
+		{"join default", numbers(1, 2), "join", nil, "1,2", "", ""},
+		{"indexOf", numbers(5, 6, 7), "indexOf", []any{objects.KulaNumber(6)}, "1", "", ""},
+		{"indexOf string value", objects.FromSlice([]any{str("a"), str("b")}), "indexOf", []any{str("b")}, "1", "", ""},
+		{"indexOf missing", numbers(5), "indexOf", []any{objects.KulaNumber(6)}, "-1", "", ""},
+		{"reverse", numbers(1, 2, 3), "reverse", nil, "[3,2,1]", "[3,2,1]", ""},
+		{"map", numbers(1, 2, 3), "map", []any{global("double")}, "[2,4,6]", "[1,2,3]", ""},
+		{"filter", numbers(1, 2, 3, 4), "filter", []any{global("even")}, "[2,4]", "", ""},
+		{"reduce", numbers(1, 2, 3), "reduce", []any{global("add")}, "6", "", ""},
+		{"reduce with init", numbers(1, 2, 3), "reduce", []any{global("add"), objects.KulaNumber(10)}, "16", "", ""},
+		{"reduce empty with init", numbers(), "reduce", []any{global("add"), objects.KulaNumber(10)}, "10", "", ""},
+		{"reduce empty", numbers(), "reduce", []any{global("add")}, "", "", "reduce of empty array"},
+		{"find", numbers(1, 3, 5), "find", []any{global("gt2")}, "3", "", ""},
+		{"find missing", numbers(1, 2), "find", []any{global("gt2")}, "null", "", ""},
+		{"some", numbers(1, 3), "some", []any{global("gt2")}, "true", "", ""},
+		{"some none", numbers(1, 2), "some", []any{global("gt2")}, "false", "", ""},
+		{"some empty", numbers(), "some", []any{global("gt2")}, "false", "", ""},
+		{"every", numbers(3, 4), "every", []any{global("gt2")}, "true", "", ""},
+		{"every not", numbers(3, 1), "every", []any{global("gt2")}, "false", "", ""},
+		{"every empty", numbers(), "every", []any{global("gt2")}, "true", "", ""},
+		{"sort default", numbers(3, 1, 2), "sort", nil, "[1,2,3]", "[1,2,3]", ""},
+		{"sort strings", objects.FromSlice([]any{str("b"), str("a")}), "sort", nil, `["a","b"]`, "", ""},
+		{"sort mixed", objects.FromSlice([]any{str("b"), objects.KulaNumber(1)}), "sort", nil, "", "", "cannot compare"},
+		{"sort stable", pairs(), "sort", []any{global("byKey")}, "[[1,1],[1,3],[1,5],[2,2],[3,0],[3,4]]", "", ""},
+		{"sort failing comparator", numbers(2, 1), "sort", []any{global("failing")}, "", "", "boom"},
+		{"callback not a function", numbers(2, 1), "map", []any{objects.KulaNumber(1)}, "", "", "can only call functions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn, _ := m.arrayProto.Lookup(tt.method)
+			got, err := m.Call(fn, tt.this, tt.args...)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := string(*objects.Stringify(got)); s != tt.want {
+				t.Errorf("result = %s, want %s", s, tt.want)
+			}
+			if s := string(*objects.Stringify(tt.this)); tt.after != "" && s != tt.after {
+				t.Errorf("receiver = %s, want %s", s, tt.after)
+			}
+		})
+	}
+}
+
+func TestArraySortMutatingComparator(t *testing.T) {
+	m := runSource(t, callbackSource)
+	arr := numbers(3, 1, 2, 5, 4)
+	popping := NewNativeFunction(func(this any, argv []any) (any, error) {
+		arr.Pop()
+		return objects.KulaNumber(argv[0].(objects.KulaNumber) - argv[1].(objects.KulaNumber)), nil
+	}, 2)
+	sort, _ := m.arrayProto.Lookup("sort")
+	_, err := m.Call(sort, arr, popping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(*objects.Stringify(arr)); s != "[1,2,3,4,5]" {
+		t.Errorf("receiver = %s, want [1,2,3,4,5]", s)
+	}
+}
+
+func TestArrayWrongReceiver(t *testing.T) {
+	m := runSource(t, callbackSource)
+	push, _ := m.arrayProto.Lookup("push")
+	_, err := m.Call(push, objects.KulaNumber(1), objects.KulaNumber(2))
+	if err == nil || !strings.Contains(err.Error(), "receiver: ") {
+		t.Fatalf("error = %v, want a receiver error", err)
+	}
+}
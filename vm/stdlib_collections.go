@@ -0,0 +1,226 @@
+package vm
+
+import (
+	"fmt"
+	"gokula/objects"
+)
+
+func (m *Machine) initCollections() {
+	m.global.Define("Map", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			entries, err := OptArg[*objects.KulaArray](argv, 0, objects.NewArray())
+			if err != nil {
+				return nil, err
+			}
+			err = m.alloc(quotaObjectKeys, len(*entries))
+			if err != nil {
+				return nil, err
+			}
+			mp := objects.NewMap()
+			for i, item := range *entries {
+				entry, ok := item.(*objects.KulaArray)
+				if !ok || len(*entry) != 2 {
+					return nil, fmt.Errorf("entry %d must be a [key, value] Array", i)
+				}
+				mp.Set((*entry)[0], (*entry)[1])
+			}
+			return mp, nil
+		}, -1,
+	))
+	m.global.Define("Set", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			items, err := OptArg[*objects.KulaArray](argv, 0, objects.NewArray())
+			if err != nil {
+				return nil, err
+			}
+			err = m.alloc(quotaObjectKeys, len(*items))
+			if err != nil {
+				return nil, err
+			}
+			set := objects.NewSet()
+			for _, item := range *items {
+				set.Add(item)
+			}
+			return set, nil
+		}, -1,
+	))
+	m.initMapProto()
+	m.initSetProto()
+}
+
+func (m *Machine) initMapProto() {
+	m.mapProto.SetNative("get", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			mp, err := This[*objects.KulaMap](this)
+			if err != nil {
+				return nil, err
+			}
+			return mp.Get(argv[0]), nil
+		}, 1,
+	))
+	m.mapProto.SetNative("set", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			mp, err := This[*objects.KulaMap](this)
+			if err != nil {
+				return nil, err
+			}
+			if !mp.Has(argv[0]) {
+				err = m.alloc(quotaObjectKeys, 1)
+				if err != nil {
+					return nil, err
+				}
+			}
+			mp.Set(argv[0], argv[1])
+			return mp, nil
+		}, 2,
+	))
+	m.mapProto.SetNative("has", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			mp, err := This[*objects.KulaMap](this)
+			if err != nil {
+				return nil, err
+			}
+			return objects.KulaBool(mp.Has(argv[0])), nil
+		}, 1,
+	))
+	m.mapProto.SetNative("delete", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			mp, err := This[*objects.KulaMap](this)
+			if err != nil {
+				return nil, err
+			}
+			return objects.KulaBool(mp.Delete(argv[0])), nil
+		}, 1,
+	))
+	m.mapProto.SetNative("size", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			mp, err := This[*objects.KulaMap](this)
+			if err != nil {
+				return nil, err
+			}
+			return mp.Size(), nil
+		}, 0,
+	))
+	m.mapProto.SetNative("keys", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			mp, err := This[*objects.KulaMap](this)
+			if err != nil {
+				return nil, err
+			}
+			keys := mp.Keys()
+			return keys, m.alloc(quotaArrayElements, len(*keys))
+		}, 0,
+	))
+	m.mapProto.SetNative("values", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			mp, err := This[*objects.KulaMap](this)
+			if err != nil {
+				return nil, err
+			}
+			values := mp.Values()
+			return values, m.alloc(quotaArrayElements, len(*values))
+		}, 0,
+	))
+	m.mapProto.SetNative("entries", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			mp, err := This[*objects.KulaMap](this)
+			if err != nil {
+				return nil, err
+			}
+			entries := mp.Entries()
+			return entries, m.alloc(quotaArrayElements, 3*len(*entries))
+		}, 0,
+	))
+	m.mapProto.SetNative("forEach", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			mp, err := This[*objects.KulaMap](this)
+			if err != nil {
+				return nil, err
+			}
+			return nil, mp.Each(func(key, value any) error {
+				_, err := m.Call(argv[0], nil, value, key)
+				return err
+			})
+		}, 1,
+	))
+}
+
+func (m *Machine) initSetProto() {
+	m.setProto.SetNative("add", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			set, err := This[*objects.KulaSet](this)
+			if err != nil {
+				return nil, err
+			}
+			if !set.Has(argv[0]) {
+				err = m.alloc(quotaObjectKeys, 1)
+				if err != nil {
+					return nil, err
+				}
+			}
+			set.Add(argv[0])
+			return set, nil
+		}, 1,
+	))
+	m.setProto.SetNative("has", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			set, err := This[*objects.KulaSet](this)
+			if err != nil {
+				return nil, err
+			}
+			return objects.KulaBool(set.Has(argv[0])), nil
+		}, 1,
+	))
+	m.setProto.SetNative("delete", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			set, err := This[*objects.KulaSet](this)
+			if err != nil {
+				return nil, err
+			}
+			return objects.KulaBool(set.Delete(argv[0])), nil
+		}, 1,
+	))
+	m.setProto.SetNative("size", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			set, err := This[*objects.KulaSet](this)
+			if err != nil {
+				return nil, err
+			}
+			return set.Size(), nil
+		}, 0,
+	))
+	values := NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			set, err := This[*objects.KulaSet](this)
+			if err != nil {
+				return nil, err
+			}
+			values := set.Values()
+			return values, m.alloc(quotaArrayElements, len(*values))
+		}, 0,
+	)
+	m.setProto.SetNative("keys", values)
+	m.setProto.SetNative("values", values)
+	m.setProto.SetNative("entries", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			set, err := This[*objects.KulaSet](this)
+			if err != nil {
+				return nil, err
+			}
+			entries := set.Entries()
+			return entries, m.alloc(quotaArrayElements, 3*len(*entries))
+		}, 0,
+	))
+	m.setProto.SetNative("forEach", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			set, err := This[*objects.KulaSet](this)
+			if err != nil {
+				return nil, err
+			}
+			return nil, set.Each(func(value any) error {
+				_, err := m.Call(argv[0], nil, value)
+				return err
+			})
+		}, 1,
+	))
+}
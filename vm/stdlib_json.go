@@ -0,0 +1,56 @@
+package vm
+
+import (
+	"fmt"
+	"gokula/objects"
+	"strings"
+)
+
+// maxJSONIndent caps the indentation of JSON.stringify like JavaScript does.
+const maxJSONIndent = 10
+
+func (m *Machine) initJSON() {
+	jsonObject := objects.NewObject()
+	jsonObject.SetNative("stringify", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			indent := ""
+			if len(argv) > 1 {
+				switch val := argv[1].(type) {
+				case nil:
+				case objects.KulaNumber:
+					indent = strings.Repeat(" ", max(0, min(maxJSONIndent, int(val))))
+				case *objects.KulaString:
+					indent = string(*val)
+					if len(indent) > maxJSONIndent {
+						indent = indent[:maxJSONIndent]
+					}
+				default:
+					return nil, fmt.Errorf("argument 1: indent must be a Number or String but '%s' given", *TypeOf(val))
+				}
+			}
+			str, err := objects.ToJSON(argv[0], indent)
+			if err != nil {
+				return nil, err
+			}
+			return m.newString(str)
+		}, 1,
+	))
+	jsonObject.SetNative("parse", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			text, err := Arg[*objects.KulaString](argv, 0)
+			if err != nil {
+				return nil, err
+			}
+			// decoded strings are never longer than the text holding them
+			err = m.alloc(quotaStringBytes, len(*text))
+			if err != nil {
+				return nil, err
+			}
+			return objects.FromJSONWith(string(*text), objects.JSONOptions{
+				AddElement: func() error { return m.alloc(quotaArrayElements, 1) },
+				AddKey:     func() error { return m.alloc(quotaObjectKeys, 1) },
+			})
+		}, 1,
+	))
+	m.global.Define("JSON", jsonObject)
+}
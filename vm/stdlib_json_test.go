@@ -0,0 +1,35 @@
+package vm
+
+import (
+	"errors"
+	"gokula/objects"
+	"strings"
+	"testing"
+)
+
+func TestJSONParseQuota(t *testing.T) {
+	cf, err := Assemble(strings.NewReader(".main\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		text   string
+		option Option
+	}{
+		{`[[1,2],[3,4]]`, WithMaxArrayElements(5)},
+		{`[{"a":1,"b":2},{"c":3}]`, WithMaxObjectKeys(2)},
+		{`"abc"`, WithMaxStringBytes(2)},
+	}
+	for _, tt := range tests {
+		m := NewMachine(cf, tt.option)
+		if err := m.Run(); err != nil {
+			t.Fatal(err)
+		}
+		json, _ := m.Global().Get("JSON")
+		parse, _ := json.(*objects.KulaObject).Lookup("parse")
+		_, err := m.Call(parse, nil, str(tt.text))
+		if !errors.Is(err, ErrQuotaExceeded) {
+			t.Errorf("parse %s: error = %v, want quota exceeded", tt.text, err)
+		}
+	}
+}
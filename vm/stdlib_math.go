@@ -0,0 +1,155 @@
+package vm
+
+import (
+	"gokula/objects"
+	"math"
+)
+
+// numberFunction wraps fn as a native taking numbers, or any count of
+// numbers when arity is -1.
+func numberFunction(fn func(args []float64) float64, arity int8) *NativeFunction {
+	return NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			if arity >= 0 {
+				argv = argv[:arity]
+			}
+			args := make([]float64, len(argv))
+			for i := range argv {
+				n, err := Arg[objects.KulaNumber](argv, i)
+				if err != nil {
+					return nil, err
+				}
+				args[i] = float64(n)
+			}
+			return objects.FromFloat64(fn(args)), nil
+		}, arity,
+	)
+}
+
+func mathUnary(fn func(float64) float64) *NativeFunction {
+	return numberFunction(func(args []float64) float64 { return fn(args[0]) }, 1)
+}
+
+func mathBinary(fn func(float64, float64) float64) *NativeFunction {
+	return numberFunction(func(args []float64) float64 { return fn(args[0], args[1]) }, 2)
+}
+
+func (m *Machine) initMath() {
+	mathObject := objects.NewObject()
+	for _, fn := range []struct {
+		name string
+		fn   func(float64) float64
+	}{
+		{"abs", math.Abs},
+		{"ceil", math.Ceil},
+		{"floor", math.Floor},
+		{"round", math.Round},
+		{"trunc", math.Trunc},
+		{"sqrt", math.Sqrt},
+		{"cbrt", math.Cbrt},
+		{"exp", math.Exp},
+		{"log", math.Log},
+		{"log2", math.Log2},
+		{"log10", math.Log10},
+		{"sin", math.Sin},
+		{"cos", math.Cos},
+		{"tan", math.Tan},
+		{"asin", math.Asin},
+		{"acos", math.Acos},
+		{"atan", math.Atan},
+		{"sinh", math.Sinh},
+		{"cosh", math.Cosh},
+		{"tanh", math.Tanh},
+		{"sign", func(x float64) float64 {
+			if x > 0 {
+				return 1
+			} else if x < 0 {
+				return -1
+			}
+			return x
+		}},
+	} {
+		mathObject.SetNative(fn.name, mathUnary(fn.fn))
+	}
+	mathObject.SetNative("pow", mathBinary(math.Pow))
+	mathObject.SetNative("atan2", mathBinary(math.Atan2))
+	mathObject.SetNative("min", numberFunction(func(args []float64) float64 {
+		result := math.Inf(1)
+		for _, x := range args {
+			if math.IsNaN(x) {
+				return x
+			}
+			result = math.Min(result, x)
+		}
+		return result
+	}, -1))
+	mathObject.SetNative("max", numberFunction(func(args []float64) float64 {
+		result := math.Inf(-1)
+		for _, x := range args {
+			if math.IsNaN(x) {
+				return x
+			}
+			result = math.Max(result, x)
+		}
+		return result
+	}, -1))
+	mathObject.SetNative("hypot", numberFunction(func(args []float64) float64 {
+		result := 0.0
+		for _, x := range args {
+			result = math.Hypot(result, x)
+		}
+		return result
+	}, -1))
+	mathObject.SetNative("isNaN", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			n, ok := argv[0].(objects.KulaNumber)
+			return objects.KulaBool(ok && math.IsNaN(float64(n))), nil
+		}, 1,
+	))
+	mathObject.SetNative("isFinite", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			n, ok := argv[0].(objects.KulaNumber)
+			return objects.KulaBool(ok && !math.IsNaN(float64(n)) && !math.IsInf(float64(n), 0)), nil
+		}, 1,
+	))
+	mathObject.SetNative("PI", objects.FromFloat64(math.Pi))
+	mathObject.SetNative("E", objects.FromFloat64(math.E))
+	mathObject.SetNative("INF", objects.FromFloat64(math.Inf(1)))
+	mathObject.SetNative("NaN", objects.FromFloat64(math.NaN()))
+	m.global.Define("Math", mathObject)
+
+	m.numberProto.SetNative("toFixed", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			n, err := This[objects.KulaNumber](this)
+			if err != nil {
+				return nil, err
+			}
+			digits, err := OptArg(argv, 0, objects.KulaNumber(0))
+			if err != nil {
+				return nil, err
+			}
+			str, err := n.ToFixed(int(digits))
+			if err != nil {
+				return nil, err
+			}
+			return m.newString(str)
+		}, 0,
+	))
+	m.numberProto.SetNative("toString", NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			n, err := This[objects.KulaNumber](this)
+			if err != nil {
+				return nil, err
+			}
+			radix, err := OptArg(argv, 0, objects.KulaNumber(10))
+			if err != nil {
+				return nil, err
+			}
+			str, err := n.ToString(int(radix))
+			if err != nil {
+				return nil, err
+			}
+			return m.newString(str)
+		}, 0,
+	))
+}
@@ -0,0 +1,247 @@
+package vm
+
+import (
+	"fmt"
+	"gokula/objects"
+	"math"
+	"unicode/utf8"
+)
+
+// maxResultLength bounds the bytes of strings built by repeat and padding,
+// which would otherwise allocate gigabytes on absurd counts. Padding is
+// charged utf8.UTFMax bytes per code point.
+const maxResultLength = 1 << 24
+
+// stringMethod wraps fn as a native of StringProto, checking its receiver.
+func stringMethod(fn func(str *objects.KulaString, argv []any) (any, error), arity int8) *NativeFunction {
+	return NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			str, err := This[*objects.KulaString](this)
+			if err != nil {
+				return nil, err
+			}
+			return fn(str, argv)
+		}, arity,
+	)
+}
+
+// stringResult accounts a freshly built string against the string quota.
+func (m *Machine) stringResult(str *objects.KulaString) (any, error) {
+	err := m.alloc(quotaStringBytes, len(*str))
+	if err != nil {
+		return nil, err
+	}
+	return str, nil
+}
+
+// substringMethod defines a method taking one string argument.
+func (m *Machine) substringMethod(name string, fn func(str, sub *objects.KulaString) any) {
+	m.stringProto.SetNative(name, stringMethod(
+		func(str *objects.KulaString, argv []any) (any, error) {
+			sub, err := Arg[*objects.KulaString](argv, 0)
+			if err != nil {
+				return nil, err
+			}
+			return fn(str, sub), nil
+		}, 1,
+	))
+}
+
+func (m *Machine) initStringProto() {
+	m.stringProto.SetNative("length", stringMethod(
+		func(str *objects.KulaString, argv []any) (any, error) {
+			return str.Length(), nil
+		}, 0,
+	))
+	m.stringProto.SetNative("at", stringMethod(
+		func(str *objects.KulaString, argv []any) (any, error) {
+			index, err := Arg[objects.KulaNumber](argv, 0)
+			if err != nil {
+				return nil, err
+			}
+			return str.At(index), nil
+		}, 1,
+	))
+	m.stringProto.SetNative("cut", stringMethod(
+		func(str *objects.KulaString, argv []any) (any, error) {
+			index, err := Arg[objects.KulaNumber](argv, 0)
+			if err != nil {
+				return nil, err
+			}
+			len, err := Arg[objects.KulaNumber](argv, 1)
+			if err != nil {
+				return nil, err
+			}
+			return str.Cut(index, len), nil
+		}, 2,
+	))
+	m.stringProto.SetNative("charCode", stringMethod(
+		func(str *objects.KulaString, argv []any) (any, error) {
+			index, err := Arg[objects.KulaNumber](argv, 0)
+			if err != nil {
+				return nil, err
+			}
+			return str.CharCode(index), nil
+		}, 1,
+	))
+	m.stringProto.SetNative("parse", stringMethod(
+		func(str *objects.KulaString, argv []any) (any, error) {
+			return str.Parse(), nil
+		}, 0,
+	))
+	m.stringProto.SetNative("split", stringMethod(
+		func(str *objects.KulaString, argv []any) (any, error) {
+			seprator, err := Arg[*objects.KulaString](argv, 0)
+			if err != nil {
+				return nil, err
+			}
+			arr := str.Split(seprator)
+			return arr, m.alloc(quotaArrayElements, len(*arr))
+		}, 1,
+	))
+	m.substringMethod("indexOf", func(str, sub *objects.KulaString) any { return str.IndexOf(sub) })
+	m.substringMethod("lastIndexOf", func(str, sub *objects.KulaString) any { return str.LastIndexOf(sub) })
+	m.substringMethod("contains", func(str, sub *objects.KulaString) any { return str.Contains(sub) })
+	m.substringMethod("startsWith", func(str, sub *objects.KulaString) any { return str.StartsWith(sub) })
+	m.substringMethod("endsWith", func(str, sub *objects.KulaString) any { return str.EndsWith(sub) })
+	m.stringProto.SetNative("replace", stringMethod(
+		func(str *objects.KulaString, argv []any) (any, error) {
+			old, err := Arg[*objects.KulaString](argv, 0)
+			if err != nil {
+				return nil, err
+			}
+			new, err := Arg[*objects.KulaString](argv, 1)
+			if err != nil {
+				return nil, err
+			}
+			return m.stringResult(str.Replace(old, new))
+		}, 2,
+	))
+	m.stringProto.SetNative("replaceAll", stringMethod(
+		func(str *objects.KulaString, argv []any) (any, error) {
+			old, err := Arg[*objects.KulaString](argv, 0)
+			if err != nil {
+				return nil, err
+			}
+			new, err := Arg[*objects.KulaString](argv, 1)
+			if err != nil {
+				return nil, err
+			}
+			return m.stringResult(str.ReplaceAll(old, new))
+		}, 2,
+	))
+	for _, method := range []struct {
+		name string
+		fn   func(*objects.KulaString) *objects.KulaString
+	}{
+		{"upper", (*objects.KulaString).Upper},
+		{"lower", (*objects.KulaString).Lower},
+		{"trim", (*objects.KulaString).Trim},
+		{"trimStart", (*objects.KulaString).TrimStart},
+		{"trimEnd", (*objects.KulaString).TrimEnd},
+	} {
+		fn := method.fn
+		m.stringProto.SetNative(method.name, stringMethod(
+			func(str *objects.KulaString, argv []any) (any, error) {
+				return m.stringResult(fn(str))
+			}, 0,
+		))
+	}
+	m.stringProto.SetNative("repeat", stringMethod(
+		func(str *objects.KulaString, argv []any) (any, error) {
+			count, err := Arg[objects.KulaNumber](argv, 0)
+			if err != nil {
+				return nil, err
+			}
+			if !isInteger(count) || count < 0 {
+				return nil, fmt.Errorf("repeat count must be a non-negative integer")
+			}
+			if float64(len(*str))*float64(count) > maxResultLength {
+				return nil, fmt.Errorf("repeat result is too long")
+			}
+			err = m.alloc(quotaStringBytes, len(*str)*int(count))
+			if err != nil {
+				return nil, err
+			}
+			return str.Repeat(int(count)), nil
+		}, 1,
+	))
+	space := objects.KulaString(" ")
+	for _, method := range []struct {
+		name string
+		fn   func(*objects.KulaString, int, *objects.KulaString) *objects.KulaString
+	}{
+		{"padStart", (*objects.KulaString).PadStart},
+		{"padEnd", (*objects.KulaString).PadEnd},
+	} {
+		fn := method.fn
+		m.stringProto.SetNative(method.name, stringMethod(
+			func(str *objects.KulaString, argv []any) (any, error) {
+				length, err := Arg[objects.KulaNumber](argv, 0)
+				if err != nil {
+					return nil, err
+				}
+				pad, err := OptArg(argv, 1, &space)
+				if err != nil {
+					return nil, err
+				}
+				if !isInteger(length) {
+					return nil, fmt.Errorf("padding length must be an integer")
+				}
+				if float64(length)*utf8.UTFMax > maxResultLength {
+					return nil, fmt.Errorf("padding result is too long")
+				}
+				err = m.alloc(quotaStringBytes, max(0, int(length))*utf8.UTFMax)
+				if err != nil {
+					return nil, err
+				}
+				return fn(str, int(length), pad), nil
+			}, 1,
+		))
+	}
+	m.stringProto.SetNative("chars", stringMethod(
+		func(str *objects.KulaString, argv []any) (any, error) {
+			arr := str.Chars()
+			return arr, m.alloc(quotaArrayElements, len(*arr))
+		}, 0,
+	))
+	m.stringProto.SetNative("graphemes", stringMethod(
+		func(str *objects.KulaString, argv []any) (any, error) {
+			arr := str.Graphemes()
+			return arr, m.alloc(quotaArrayElements, len(*arr))
+		}, 0,
+	))
+	m.stringProto.SetNative("graphemeLength", stringMethod(
+		func(str *objects.KulaString, argv []any) (any, error) {
+			return str.GraphemeLength(), nil
+		}, 0,
+	))
+	m.stringProto.SetNative("codePoints", stringMethod(
+		func(str *objects.KulaString, argv []any) (any, error) {
+			arr := str.CodePoints()
+			return arr, m.alloc(quotaArrayElements, len(*arr))
+		}, 0,
+	))
+
+	fromCharCode := NewNativeFunction(
+		func(this any, argv []any) (any, error) {
+			codes := make([]objects.KulaNumber, len(argv))
+			for i := range argv {
+				code, err := Arg[objects.KulaNumber](argv, i)
+				if err != nil {
+					return nil, err
+				}
+				codes[i] = code
+			}
+			return m.stringResult(objects.FromCharCodes(codes...))
+		}, -1,
+	)
+	// the receiver is ignored, so the global alias needs no string to call it on
+	m.stringProto.SetNative("fromCharCode", fromCharCode)
+	m.global.Define("fromCharCode", fromCharCode)
+}
+
+// isInteger reports whether n is a finite whole number.
+func isInteger(n objects.KulaNumber) bool {
+	return float64(n) == math.Trunc(float64(n)) && !math.IsInf(float64(n), 0)
+}
@@ -0,0 +1,100 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type Frame struct {
+	Function int    // -1 for the main chunk
+	Name     string // best-effort name of the function
+	Ip       int
+	Pos      *SourcePos // nil without debug info
+}
+
+func (f Frame) String() string {
+	block := "main"
+	if f.Function >= 0 {
+		block = fmt.Sprintf("F %d", f.Function)
+	}
+	if f.Pos != nil {
+		return fmt.Sprintf("at %s (%s) [%s:%d]", f.Name, f.Pos, block, f.Ip)
+	}
+	return fmt.Sprintf("at %s [%s:%d]", f.Name, block, f.Ip)
+}
+
+// RuntimeError is returned by Machine.Run when a script fails. Its Trace
+// starts at the failing instruction and ends in the main chunk.
+type RuntimeError struct {
+	Err   error
+	Trace []Frame
+}
+
+func (e *RuntimeError) Error() string {
+	if len(e.Trace) > 0 && e.Trace[0].Pos != nil {
+		return fmt.Sprintf("%s: %s", e.Trace[0].Pos, e.Err.Error())
+	}
+	return e.Err.Error()
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+func (e *RuntimeError) StackTrace() string {
+	var sb strings.Builder
+	for _, frame := range e.Trace {
+		sb.WriteString("\t")
+		sb.WriteString(frame.String())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func (m *Machine) newRuntimeError(err error) *RuntimeError {
+	var runtimeError *RuntimeError
+	if errors.As(err, &runtimeError) {
+		// raised by a nested Call, whose trace reaches deeper
+		return runtimeError
+	}
+	trace := make([]Frame, 0, m.callStack.Size()+1)
+	trace = append(trace, m.frame(m.fp, m.ip))
+	for i := m.callStack.Size() - 1; i >= 0; i-- {
+		callInfo := m.callStack[i]
+		trace = append(trace, m.frame(callInfo.Fp, callInfo.Ip))
+	}
+	return &RuntimeError{err, trace}
+}
+
+func (m *Machine) frame(fp, ip int) Frame {
+	f := Frame{Function: fp, Name: m.file.functionName(fp), Ip: ip}
+	if pos, ok := m.file.Debug.Position(fp, ip); ok {
+		f.Pos = &pos
+	}
+	return f
+}
+
+// functionName guesses the name of function fIndex from a FUNC instruction
+// directly followed by a DECL or ASGN of a symbol.
+func (kulac *CompiledFile) functionName(fIndex int) string {
+	if fIndex < 0 {
+		return "<main>"
+	}
+	blocks := [][]Instruction{kulac.Chunk}
+	for _, function := range kulac.Functions {
+		blocks = append(blocks, function.Instructions)
+	}
+	for _, instructions := range blocks {
+		for i := 0; i+1 < len(instructions); i++ {
+			if instructions[i].Op != FUNC || instructions[i].Val != fIndex {
+				continue
+			}
+			next := instructions[i+1]
+			if (next.Op == DECL || next.Op == ASGN) && next.Val < len(kulac.SymbolArray) {
+				return kulac.SymbolArray[next.Val]
+			}
+		}
+	}
+	return fmt.Sprintf("<F %d>", fIndex)
+}
@@ -0,0 +1,204 @@
+package vm
+
+import "fmt"
+
+type VerifyError struct {
+	Function int // -1 for the main chunk
+	Index    int // -1 for problems not tied to an instruction
+	Message  string
+}
+
+func (e VerifyError) Error() string {
+	block := "main"
+	if e.Function >= 0 {
+		block = fmt.Sprintf("F %d", e.Function)
+	}
+	if e.Index < 0 {
+		return fmt.Sprintf("[%s] %s", block, e.Message)
+	}
+	return fmt.Sprintf("[%s:%d] %s", block, e.Index, e.Message)
+}
+
+// Verify checks operand indices, jump targets, operand stack balance and
+// ENVST/ENVED nesting of every block, so that a verified file cannot make
+// the machine index out of range.
+func Verify(kulac *CompiledFile) []VerifyError {
+	errs := make([]VerifyError, 0)
+	if len(kulac.Literals) < literalBase {
+		errs = append(errs, VerifyError{-1, -1, "literal table is missing the implicit literals"})
+	}
+	errs = kulac.verifyBlock(errs, -1, kulac.Chunk)
+	for fIndex, function := range kulac.Functions {
+		for _, param := range function.Params {
+			if int(param) >= len(kulac.SymbolArray) {
+				errs = append(errs, VerifyError{fIndex, -1, fmt.Sprintf("param symbol %d out of range", param)})
+			}
+		}
+		errs = kulac.verifyBlock(errs, fIndex, function.Instructions)
+	}
+	if kulac.Debug != nil {
+		errs = kulac.verifyDebug(errs)
+	}
+	return errs
+}
+
+func (kulac *CompiledFile) verifyDebug(errs []VerifyError) []VerifyError {
+	if len(kulac.Debug.Functions) > len(kulac.Functions) {
+		errs = append(errs, VerifyError{-1, -1, "debug info has more blocks than functions"})
+	}
+	for fIndex := -1; fIndex < len(kulac.Functions); fIndex++ {
+		size := len(kulac.Chunk)
+		if fIndex >= 0 {
+			size = len(kulac.Functions[fIndex].Instructions)
+		}
+		for i, entry := range kulac.Debug.block(fIndex) {
+			if entry.Index > size {
+				errs = append(errs, VerifyError{fIndex, entry.Index, "debug line entry out of range"})
+			}
+			if entry.File < 0 || entry.File >= len(kulac.Debug.Files) {
+				errs = append(errs, VerifyError{fIndex, entry.Index, fmt.Sprintf("debug file %d out of range", entry.File)})
+			}
+			if i > 0 && kulac.Debug.block(fIndex)[i-1].Index >= entry.Index {
+				errs = append(errs, VerifyError{fIndex, entry.Index, "debug line entries are not sorted"})
+			}
+		}
+	}
+	return errs
+}
+
+func (kulac *CompiledFile) verifyBlock(errs []VerifyError, fIndex int, instructions []Instruction) []VerifyError {
+	report := func(index int, format string, a ...any) {
+		errs = append(errs, VerifyError{fIndex, index, fmt.Sprintf(format, a...)})
+	}
+
+	// Operands
+	valid := true
+	for i, inst := range instructions {
+		if !fitsOperand(inst.Op, inst.Val) {
+			report(i, "%s operand %d does not fit in %d bits", inst.Op, inst.Val, codeSize(inst.Op))
+			valid = false
+			continue
+		}
+		var limit int
+		switch inst.Op {
+		case LOADC:
+			limit = len(kulac.Literals)
+		case LOAD, DECL, ASGN:
+			limit = len(kulac.SymbolArray)
+		case FUNC:
+			limit = len(kulac.Functions)
+		case JMP, JMPT, JMPF, TRY:
+			limit = len(instructions) + 1
+		case RET, RETV:
+			if fIndex < 0 {
+				report(i, "%s outside of function", inst.Op)
+				valid = false
+			}
+			continue
+		default:
+			if inst.Op.String() == "" {
+				report(i, "unknown opcode 0x%x", byte(inst.Op))
+				valid = false
+			}
+			continue
+		}
+		if inst.Val < 0 || inst.Val >= limit {
+			report(i, "%s operand %d out of range", inst.Op, inst.Val)
+			valid = false
+		}
+	}
+	if !valid {
+		return errs
+	}
+
+	// Stack depth and environment depth
+	type state struct{ stack, env int }
+	states := make([]state, len(instructions)+1)
+	for i := range states {
+		states[i] = state{-1, -1}
+	}
+	work := []int{0}
+	states[0] = state{0, 0}
+	for len(work) > 0 {
+		i := work[len(work)-1]
+		work = work[:len(work)-1]
+		if i == len(instructions) {
+			continue
+		}
+		inst := instructions[i]
+		pops, pushes := stackEffect(inst)
+		if states[i].stack < pops {
+			report(i, "%s needs %d operands but stack has %d", inst.Op, pops, states[i].stack)
+			continue
+		}
+		cur := state{states[i].stack - pops + pushes, states[i].env}
+		switch inst.Op {
+		case ENVST:
+			cur.env++
+		case ENVED:
+			if cur.env == 0 {
+				report(i, "ENVED without matching ENVST")
+				continue
+			}
+			cur.env--
+		}
+
+		type edge struct {
+			to int
+			state
+		}
+		var next []edge
+		switch inst.Op {
+		case JMP:
+			next = []edge{{inst.Val, cur}}
+		case JMPT, JMPF:
+			next = []edge{{i + 1, cur}, {inst.Val, cur}}
+		case TRY:
+			// the handler starts with the thrown value on the stack
+			next = []edge{{i + 1, cur}, {inst.Val, state{cur.stack + 1, cur.env}}}
+		case RET, RETV, THROW:
+		default:
+			next = []edge{{i + 1, cur}}
+		}
+		for _, n := range next {
+			if states[n.to].stack < 0 {
+				states[n.to] = n.state
+				work = append(work, n.to)
+			} else if states[n.to].stack != n.stack {
+				report(n.to, "inconsistent stack depth %d and %d", states[n.to].stack, n.stack)
+			} else if states[n.to].env != n.env {
+				report(n.to, "inconsistent environment depth %d and %d", states[n.to].env, n.env)
+			}
+		}
+	}
+	return errs
+}
+
+func stackEffect(inst Instruction) (pops, pushes int) {
+	switch inst.Op {
+	case LOADC, LOAD, FUNC:
+		return 0, 1
+	case DECL, ASGN, NEG, NOT:
+		return 1, 1
+	case POP, JMPT, JMPF, RETV, THROW:
+		return 1, 0
+	case DUP:
+		return 1, 2
+	case CALL:
+		return inst.Val + 1, 1
+	case CALWT:
+		return inst.Val + 2, 1
+	case GET:
+		return 2, 1
+	case GETWT:
+		return 2, 2
+	case SET:
+		return 3, 1
+	case ADD, SUB, MUL, DIV, MOD, EQ, NEQ, LT, LE, GT, GE:
+		return 2, 1
+	case PRINT:
+		return inst.Val, 0
+	default:
+		return 0, 0
+	}
+}
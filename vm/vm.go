@@ -1,169 +1,346 @@
 package vm
 
 import (
+	"context"
 	"fmt"
 	"gokula/objects"
 	"gokula/utils"
 	"strings"
+	"time"
 )
 
-var global *Context
-var context *Context
-var vmStack utils.Stack[*utils.Stack[any]]
-var currentStack *utils.Stack[any]
-var callStack utils.Stack[CallInfo]
-
-var ip int
-var fp int
-
 type CallInfo struct {
 	Ip, Fp  int
 	Context *Context
 }
 
-func initVM() {
-	global = NewContext(nil)
-	context = global
-	vmStack = utils.NewStack[*utils.Stack[any]]()
+type Machine struct {
+	file         *CompiledFile
+	global       *Context
+	context      *Context
+	vmStack      utils.Stack[*utils.Stack[any]]
+	currentStack *utils.Stack[any]
+	callStack    utils.Stack[CallInfo]
+
+	objectProto *objects.KulaObject
+	stringProto *objects.KulaObject
+	arrayProto  *objects.KulaObject
+	numberProto *objects.KulaObject
+	boolProto   *objects.KulaObject
+	errorProto  *objects.KulaObject
+	mapProto    *objects.KulaObject
+	setProto    *objects.KulaObject
+
+	handlers utils.Stack[handler]
+
+	ip       int
+	fp       int
+	verified bool
+
+	maxInstructions int64
+	deadline        time.Time
+	ctx             context.Context
+	steps           int64
+
+	maxCallDepth int
+	maxStackSize int
+	quotaLimits  [quotaKinds]int64
+	quotaUsed    [quotaKinds]int64
+
+	capabilities map[Capability]bool
+	args         []string
+}
+
+func NewMachine(cf *CompiledFile, opts ...Option) *Machine {
+	m := new(Machine)
+	m.file = cf
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.global = NewContext(nil)
+	m.reset()
+
+	// Prototypes are owned by the machine so that concurrent runs never share them.
+	m.objectProto = objects.NewObject()
+	m.stringProto = objects.NewObject()
+	m.arrayProto = objects.NewObject()
+	m.numberProto = objects.NewObject()
+	m.boolProto = objects.NewObject()
+	m.errorProto = objects.NewObject()
+	m.mapProto = objects.NewObject()
+	m.setProto = objects.NewObject()
+
+	// Standard Library
+	m.initStdlib()
+	return m
+}
+
+func (m *Machine) reset() {
+	m.context = m.global
+	m.vmStack = utils.NewStack[*utils.Stack[any]]()
 	innerStack := utils.NewStack[any]()
-	currentStack = &innerStack
-	vmStack.Push(currentStack)
-	callStack = utils.NewStack[CallInfo]()
-	ip = 0
-	fp = -1
+	m.currentStack = &innerStack
+	m.vmStack.Push(m.currentStack)
+	m.callStack = utils.NewStack[CallInfo]()
+	m.handlers = utils.NewStack[handler]()
+	m.steps = 0
+	m.quotaUsed = [quotaKinds]int64{}
+	m.ip = 0
+	m.fp = -1
+}
+
+func (m *Machine) File() *CompiledFile {
+	return m.file
+}
+
+func (m *Machine) Global() *Context {
+	return m.global
 }
 
 func (cf *CompiledFile) Run() error {
-	initVM()
+	return NewMachine(cf).Run()
+}
 
-	// Standard Library
-	initStdlib()
+func (m *Machine) Run() error {
+	err := m.verify()
+	if err != nil {
+		return err
+	}
+	m.reset()
+	return m.loop(-1)
+}
 
-	for {
-		var ins *Instruction
-		if fp >= 0 {
-			ins = &CompiledFileInstance.Functions[fp].Instructions[ip]
-		} else {
-			ins = &CompiledFileInstance.Chunk[ip]
+func (m *Machine) verify() error {
+	if !m.verified {
+		errs := Verify(m.file)
+		if len(errs) > 0 {
+			return fmt.Errorf("kulac file failed verification with %d error(s), first: %w", len(errs), errs[0])
 		}
-		// fmt.Println("Do", ins, "in [F", fp, "]")
-		err := ins.run()
+		m.verified = true
+	}
+	return nil
+}
+
+// loop runs instructions until the main chunk ends or, when base is not
+// negative, until the call stack returns to base frames. On failure the
+// machine is unwound to base before the RuntimeError is returned.
+func (m *Machine) loop(base int) error {
+	for {
+		err := m.checkBudget()
 		if err != nil {
-			return err
+			return m.fail(err, base)
 		}
-		ip++
-		if fp >= 0 {
-			if ip >= len(CompiledFileInstance.Functions[fp].Instructions) {
-				vmStack.Pop().Clear()
-				currentStack = vmStack.Peek()
-				currentStack.Push(nil)
-				callInfo := callStack.Pop()
-				ip = callInfo.Ip
-				fp = callInfo.Fp
-				context = callInfo.Context
-				ip++
+		code := m.code()
+		if m.ip >= len(code) {
+			if m.fp < 0 {
+				return nil
 			}
-		} else {
-			if ip >= len(CompiledFileInstance.Chunk) {
-				break
+			m.ret(nil)
+			if m.callStack.Size() == base {
+				return nil
 			}
+			m.ip++
+			continue
 		}
+		// fmt.Println("Do", code[m.ip], "in [F", m.fp, "]")
+		err = m.exec(&code[m.ip])
+		if err == nil && m.maxStackSize > 0 && m.currentStack.Size() > m.maxStackSize {
+			err = fmt.Errorf("%w: operand stack deeper than %d", ErrQuotaExceeded, m.maxStackSize)
+		}
+		if err != nil && !m.throw(err, base) {
+			return m.fail(err, base)
+		}
+		if m.callStack.Size() == base {
+			return nil
+		}
+		m.ip++
 	}
+}
 
-	return nil
+// fail records the trace of err and unwinds the frames above base.
+func (m *Machine) fail(err error, base int) error {
+	runtimeError := m.newRuntimeError(err)
+	if base >= 0 && m.callStack.Size() > base {
+		callInfo := m.callStack[base]
+		for m.callStack.Size() > base {
+			m.vmStack.Pop().Clear()
+			m.callStack.Pop()
+		}
+		m.currentStack = m.vmStack.Peek()
+		m.ip = callInfo.Ip
+		m.fp = callInfo.Fp
+		m.context = callInfo.Context
+		for !m.handlers.Empty() && m.handlers.Peek().callDepth > base {
+			m.handlers.Pop()
+		}
+	}
+	return runtimeError
 }
 
-func (ins *Instruction) run() error {
+// Call runs a Kula function to completion and returns its result. It can be
+// used by the embedder around Run, or by native functions during Run to
+// invoke callbacks. Go arguments are converted with ToKula.
+func (m *Machine) Call(fn any, this any, args ...any) (any, error) {
+	err := m.verify()
+	if err != nil {
+		return nil, err
+	}
+	argv := make([]any, len(args))
+	for i, arg := range args {
+		argv[i], err = m.ToKula(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i, err)
+		}
+	}
+
+	if object, ok := fn.(*objects.KulaObject); ok {
+		key := objects.FUNC__
+		fn = object.Get((*objects.KulaString)(&key))
+	}
+	switch function := fn.(type) {
+	case *NativeFunction:
+		function.CallSite = this
+		return function.calcNativeFunction(argv)
+	case *VMFunction:
+		base := m.callStack.Size()
+		function.CallSite = this
+		err = m.calcVMFunction(function, argv)
+		if err != nil {
+			return nil, err
+		}
+		m.ip++
+		err = m.loop(base)
+		if err != nil {
+			return nil, err
+		}
+		return m.currentStack.Pop(), nil
+	}
+	return nil, fmt.Errorf("can only call functions")
+}
+
+func (m *Machine) code() []Instruction {
+	if m.fp >= 0 {
+		return m.file.Functions[m.fp].Instructions
+	}
+	return m.file.Chunk
+}
+
+// ret leaves the current function, pushing value onto the caller's stack.
+func (m *Machine) ret(value any) {
+	m.vmStack.Pop().Clear()
+	m.currentStack = m.vmStack.Peek()
+	m.currentStack.Push(value)
+	callInfo := m.callStack.Pop()
+	m.ip = callInfo.Ip
+	m.fp = callInfo.Fp
+	m.context = callInfo.Context
+	for !m.handlers.Empty() && m.handlers.Peek().callDepth > m.callStack.Size() {
+		m.handlers.Pop()
+	}
+}
+
+func (m *Machine) exec(ins *Instruction) error {
 	switch ins.Op {
 	case LOADC:
-		currentStack.Push(CompiledFileInstance.Literals[ins.Val])
+		m.currentStack.Push(m.file.Literals[ins.Val])
 	case LOAD:
-		v, err := context.Get(CompiledFileInstance.SymbolArray[ins.Val])
+		v, err := m.context.Get(m.file.SymbolArray[ins.Val])
 		if err != nil {
 			return err
 		}
-		currentStack.Push(v)
+		m.currentStack.Push(v)
 	case DECL:
-		top := currentStack.Peek()
-		context.Define(CompiledFileInstance.SymbolArray[ins.Val], top)
+		top := m.currentStack.Peek()
+		m.context.Define(m.file.SymbolArray[ins.Val], top)
 	case ASGN:
-		top := currentStack.Peek()
-		context.Assgin(CompiledFileInstance.SymbolArray[ins.Val], top)
+		top := m.currentStack.Peek()
+		err := m.context.Assgin(m.file.SymbolArray[ins.Val], top)
+		if err != nil {
+			return err
+		}
 	case POP:
-		currentStack.Pop()
+		m.currentStack.Pop()
 	case DUP:
-		currentStack.Push(currentStack.Peek())
+		m.currentStack.Push(m.currentStack.Peek())
 	case FUNC:
-		f := NewFunction(ins.Val, context)
-		currentStack.Push(f)
+		f := NewFunction(ins.Val, m.context)
+		m.currentStack.Push(f)
 	case RET:
-		vmStack.Pop().Clear()
-		currentStack = vmStack.Peek()
-		currentStack.Push(nil)
-		callInfo := callStack.Pop()
-		ip = callInfo.Ip
-		fp = callInfo.Fp
-		context = callInfo.Context
+		m.ret(nil)
 	case RETV:
-		top := currentStack.Pop()
-		vmStack.Pop().Clear()
-		currentStack = vmStack.Peek()
-		currentStack.Push(top)
-		callInfo := callStack.Pop()
-		ip = callInfo.Ip
-		fp = callInfo.Fp
-		context = callInfo.Context
+		m.ret(m.currentStack.Pop())
 	case ENVST:
-		context = NewContext(context)
+		m.context = NewContext(m.context)
 	case ENVED:
-		context = context.enclosing
+		m.context = m.context.enclosing
+	case TRY:
+		m.handlers.Push(handler{
+			target:    ins.Val,
+			fp:        m.fp,
+			callDepth: m.callStack.Size(),
+			stackSize: m.currentStack.Size(),
+			context:   m.context,
+		})
+	case ENDTRY:
+		if !m.handlers.Empty() && m.handlers.Peek().callDepth == m.callStack.Size() {
+			m.handlers.Pop()
+		}
+	case THROW:
+		return m.newThrownError(m.currentStack.Pop())
 	case GET:
-		key := currentStack.Pop()
-		container := currentStack.Pop()
-		value, err := evalGet(container, key, ins)
+		key := m.currentStack.Pop()
+		container := m.currentStack.Pop()
+		value, err := m.evalGet(container, key)
 		if err != nil {
 			return err
 		}
-		currentStack.Push(value)
+		m.currentStack.Push(value)
 	case GETWT:
-		key := currentStack.Pop()
-		container := currentStack.Pop()
-		value, err := evalGet(container, key, ins)
+		key := m.currentStack.Pop()
+		container := m.currentStack.Pop()
+		value, err := m.evalGet(container, key)
 		if err != nil {
 			return err
 		}
-		currentStack.Push(container)
-		currentStack.Push(value)
+		m.currentStack.Push(container)
+		m.currentStack.Push(value)
 	case SET:
-		value := currentStack.Pop()
-		key := currentStack.Pop()
-		container := currentStack.Pop()
-		err := evalSet(container, key, value)
+		value := m.currentStack.Pop()
+		key := m.currentStack.Pop()
+		container := m.currentStack.Pop()
+		err := m.evalSet(container, key, value)
 		if err != nil {
 			return err
 		}
-		currentStack.Push(value)
+		m.currentStack.Push(value)
 	case CALL:
 		argc := ins.Val
 		argv := make([]any, argc)
 		for c := argc - 1; c >= 0; c -= 1 {
-			argv[c] = currentStack.Pop()
+			argv[c] = m.currentStack.Pop()
 		}
-		function := currentStack.Pop()
+		function := m.currentStack.Pop()
 
 		if vmf, ok := function.(*VMFunction); ok {
-			vmf.calcVMFunction(argv)
+			err := m.calcVMFunction(vmf, argv)
+			if err != nil {
+				return err
+			}
 		} else if nf, ok := function.(*NativeFunction); ok {
+			nf.CallSite = nil
 			val, err := nf.calcNativeFunction(argv)
 			if err != nil {
 				return err
 			}
-			currentStack.Push(val)
+			m.currentStack.Push(val)
 		} else if object, ok := function.(*objects.KulaObject); ok {
 			key := objects.FUNC__
 			functionSugar := object.Get((*objects.KulaString)(&key))
 			if vmf, ok := functionSugar.(*VMFunction); ok {
-				vmf.calcVMFunction(argv)
+				err := m.calcVMFunction(vmf, argv)
+				if err != nil {
+					return err
+				}
 			} else {
 				return fmt.Errorf("object has no such function")
 			}
@@ -174,27 +351,33 @@ func (ins *Instruction) run() error {
 		argc := ins.Val
 		argv := make([]any, argc)
 		for c := argc - 1; c >= 0; c -= 1 {
-			argv[c] = currentStack.Pop()
+			argv[c] = m.currentStack.Pop()
 		}
-		function := currentStack.Pop()
-		callSite := currentStack.Pop()
+		function := m.currentStack.Pop()
+		callSite := m.currentStack.Pop()
 
 		if vmf, ok := function.(*VMFunction); ok {
 			vmf.CallSite = callSite
-			vmf.calcVMFunction(argv)
+			err := m.calcVMFunction(vmf, argv)
+			if err != nil {
+				return err
+			}
 		} else if nf, ok := function.(*NativeFunction); ok {
 			nf.CallSite = callSite
 			val, err := nf.calcNativeFunction(argv)
 			if err != nil {
 				return err
 			}
-			currentStack.Push(val)
+			m.currentStack.Push(val)
 		} else if object, ok := function.(*objects.KulaObject); ok {
 			key := objects.FUNC__
 			functionSugar := object.Get((*objects.KulaString)(&key))
 			if vmf, ok := functionSugar.(*VMFunction); ok {
-				vmf.CallSite = &callSite
-				vmf.calcVMFunction(argv)
+				vmf.CallSite = callSite
+				err := m.calcVMFunction(vmf, argv)
+				if err != nil {
+					return err
+				}
 			} else {
 				return fmt.Errorf("object has no such function")
 			}
@@ -204,128 +387,132 @@ func (ins *Instruction) run() error {
 	case PRINT:
 		ls := make([]string, ins.Val)
 		for t := ins.Val - 1; t >= 0; t-- {
-			ls[t] = string(*objects.Stringify(currentStack.Pop()))
+			ls[t] = string(*objects.Stringify(m.currentStack.Pop()))
 		}
 		fmt.Println(strings.Join(ls, " "))
 	case JMP:
-		ip = ins.Val - 1
+		m.ip = ins.Val - 1
 	case JMPT:
-		if objects.Booleanify(currentStack.Pop()) {
-			ip = ins.Val - 1
+		if objects.Booleanify(m.currentStack.Pop()) {
+			m.ip = ins.Val - 1
 		}
 	case JMPF:
-		if !objects.Booleanify(currentStack.Pop()) {
-			ip = ins.Val - 1
+		if !objects.Booleanify(m.currentStack.Pop()) {
+			m.ip = ins.Val - 1
 		}
 	// calculating
 	case ADD:
-		v2 := currentStack.Pop()
-		v1 := currentStack.Pop()
+		v2 := m.currentStack.Pop()
+		v1 := m.currentStack.Pop()
 		if n1, ok := v1.(objects.KulaNumber); ok {
 			if n2, ok := v2.(objects.KulaNumber); ok {
-				currentStack.Push(n1 + n2)
+				m.currentStack.Push(n1 + n2)
 				break
 			}
 		}
 		if s1, ok := v1.(*objects.KulaString); ok {
 			if s2, ok := v2.(*objects.KulaString); ok {
+				err := m.alloc(quotaStringBytes, len(*s1)+len(*s2))
+				if err != nil {
+					return err
+				}
 				str := *s1 + *s2
-				currentStack.Push(&str)
+				m.currentStack.Push(&str)
 				break
 			}
 		}
 		return fmt.Errorf("operands must be 2 numbers or 2 strings")
 	case SUB:
-		v2 := currentStack.Pop()
-		v1 := currentStack.Pop()
+		v2 := m.currentStack.Pop()
+		v1 := m.currentStack.Pop()
 		if n1, ok := v1.(objects.KulaNumber); ok {
 			if n2, ok := v2.(objects.KulaNumber); ok {
-				currentStack.Push(n1 - n2)
+				m.currentStack.Push(n1 - n2)
 				break
 			}
 		}
 		return fmt.Errorf("operands must be 2 numbers")
 	case MUL:
-		v2 := currentStack.Pop()
-		v1 := currentStack.Pop()
+		v2 := m.currentStack.Pop()
+		v1 := m.currentStack.Pop()
 		if n1, ok := v1.(objects.KulaNumber); ok {
 			if n2, ok := v2.(objects.KulaNumber); ok {
-				currentStack.Push(n1 * n2)
+				m.currentStack.Push(n1 * n2)
 				break
 			}
 		}
 		return fmt.Errorf("operands must be 2 numbers")
 	case DIV:
-		v2 := currentStack.Pop()
-		v1 := currentStack.Pop()
+		v2 := m.currentStack.Pop()
+		v1 := m.currentStack.Pop()
 		if n1, ok := v1.(objects.KulaNumber); ok {
 			if n2, ok := v2.(objects.KulaNumber); ok {
-				currentStack.Push(n1 / n2)
+				m.currentStack.Push(n1 / n2)
 				break
 			}
 		}
 		return fmt.Errorf("operands must be 2 numbers")
 	case MOD:
-		v2 := currentStack.Pop()
-		v1 := currentStack.Pop()
+		v2 := m.currentStack.Pop()
+		v1 := m.currentStack.Pop()
 		if n1, ok := v1.(objects.KulaNumber); ok {
 			if n2, ok := v2.(objects.KulaNumber); ok {
-				currentStack.Push(objects.KulaNumber(int(n1) % int(n2)))
+				m.currentStack.Push(objects.KulaNumber(int(n1) % int(n2)))
 				break
 			}
 		}
 		return fmt.Errorf("operands must be 2 numbers")
 	case GT:
-		v2 := currentStack.Pop()
-		v1 := currentStack.Pop()
+		v2 := m.currentStack.Pop()
+		v1 := m.currentStack.Pop()
 		if n1, ok := v1.(objects.KulaNumber); ok {
 			if n2, ok := v2.(objects.KulaNumber); ok {
-				currentStack.Push(objects.KulaBool(n1 > n2))
+				m.currentStack.Push(objects.KulaBool(n1 > n2))
 				break
 			}
 		}
 		return fmt.Errorf("operands must be 2 numbers")
 	case GE:
-		v2 := currentStack.Pop()
-		v1 := currentStack.Pop()
+		v2 := m.currentStack.Pop()
+		v1 := m.currentStack.Pop()
 		if n1, ok := v1.(objects.KulaNumber); ok {
 			if n2, ok := v2.(objects.KulaNumber); ok {
-				currentStack.Push(objects.KulaBool(n1 >= n2))
+				m.currentStack.Push(objects.KulaBool(n1 >= n2))
 				break
 			}
 		}
 		return fmt.Errorf("operands must be 2 numbers")
 	case LT:
-		v2 := currentStack.Pop()
-		v1 := currentStack.Pop()
+		v2 := m.currentStack.Pop()
+		v1 := m.currentStack.Pop()
 		if n1, ok := v1.(objects.KulaNumber); ok {
 			if n2, ok := v2.(objects.KulaNumber); ok {
-				currentStack.Push(objects.KulaBool(n1 < n2))
+				m.currentStack.Push(objects.KulaBool(n1 < n2))
 				break
 			}
 		}
 		return fmt.Errorf("operands must be 2 numbers")
 	case LE:
-		v2 := currentStack.Pop()
-		v1 := currentStack.Pop()
+		v2 := m.currentStack.Pop()
+		v1 := m.currentStack.Pop()
 		if n1, ok := v1.(objects.KulaNumber); ok {
 			if n2, ok := v2.(objects.KulaNumber); ok {
-				currentStack.Push(objects.KulaBool(n1 <= n2))
+				m.currentStack.Push(objects.KulaBool(n1 <= n2))
 				break
 			}
 		}
 		return fmt.Errorf("operands must be 2 numbers")
 	case EQ:
-		v2 := currentStack.Pop()
-		v1 := currentStack.Pop()
-		currentStack.Push(objects.KulaBool(v1 == v2))
+		v2 := m.currentStack.Pop()
+		v1 := m.currentStack.Pop()
+		m.currentStack.Push(objects.KulaBool(objects.Equal(v1, v2)))
 	case NEQ:
-		v2 := currentStack.Pop()
-		v1 := currentStack.Pop()
-		currentStack.Push(objects.KulaBool(v1 != v2))
+		v2 := m.currentStack.Pop()
+		v1 := m.currentStack.Pop()
+		m.currentStack.Push(objects.KulaBool(!objects.Equal(v1, v2)))
 	case NEG:
-		top := currentStack.Pop()
-		currentStack.Push(objects.Booleanify(top))
+		top := m.currentStack.Pop()
+		m.currentStack.Push(objects.Booleanify(top))
 	default:
 		fmt.Println("err: ", ins.Op.String())
 	}
@@ -333,36 +520,64 @@ func (ins *Instruction) run() error {
 	return nil
 }
 
-func evalGet(container any, key any, ins *Instruction) (any, error) {
+// lookup resolves key on proto, falling back to the machine's object prototype.
+func (m *Machine) lookup(proto *objects.KulaObject, key *objects.KulaString) any {
+	if val, ok := proto.Lookup(string(*key)); ok {
+		return val
+	}
+	return m.objectProto.Get(key)
+}
+
+func (m *Machine) evalGet(container any, key any) (any, error) {
 	if object, ok := container.(*objects.KulaObject); ok {
 		if keyString, ok := key.(*objects.KulaString); ok {
-			return object.Get(keyString), nil
+			return m.lookup(object, keyString), nil
 		}
 		return nil, fmt.Errorf("index of 'Object' can only be 'String'")
 	} else if array, ok := container.(*objects.KulaArray); ok {
 		if keyNumber, ok := key.(objects.KulaNumber); ok {
 			return array.Get(keyNumber), nil
 		} else if keyString, ok := key.(*objects.KulaString); ok {
-			return objects.ArrayProto.Get(keyString), nil
+			return m.lookup(m.arrayProto, keyString), nil
 		}
 		return nil, fmt.Errorf("index of 'Array' can only be 'Number'")
+	} else if mp, ok := container.(*objects.KulaMap); ok {
+		// methods shadow string keys, which remain reachable through get
+		if keyString, ok := key.(*objects.KulaString); ok {
+			if val, ok := m.mapProto.Lookup(string(*keyString)); ok {
+				return val, nil
+			}
+		}
+		return mp.Get(key), nil
+	} else if _, ok := container.(*objects.KulaSet); ok {
+		if keyString, ok := key.(*objects.KulaString); ok {
+			val, _ := m.setProto.Lookup(string(*keyString))
+			return val, nil
+		}
+		return nil, fmt.Errorf("index of 'Set' can only be 'String'")
 	}
 
 	if keyString, ok := key.(*objects.KulaString); ok {
 		if _, ok := container.(*objects.KulaString); ok {
-			return objects.StringProto.Get(keyString), nil
+			return m.lookup(m.stringProto, keyString), nil
 		} else if _, ok := container.(objects.KulaNumber); ok {
-			return objects.NumberProto.Get(keyString), nil
+			return m.lookup(m.numberProto, keyString), nil
 		} else if _, ok := container.(objects.KulaBool); ok {
-			return objects.BoolProto.Get(keyString), nil
+			return m.lookup(m.boolProto, keyString), nil
 		}
 	}
 	return nil, fmt.Errorf("what do you want to get?")
 }
 
-func evalSet(container any, key, value any) error {
+func (m *Machine) evalSet(container any, key, value any) error {
 	if object, ok := container.(*objects.KulaObject); ok {
 		if keyString, ok := key.(*objects.KulaString); ok {
+			if _, ok := object.Own(string(*keyString)); !ok {
+				err := m.alloc(quotaObjectKeys, 1)
+				if err != nil {
+					return err
+				}
+			}
 			object.Set(keyString, value)
 			return nil
 		}
@@ -373,35 +588,52 @@ func evalSet(container any, key, value any) error {
 			return nil
 		}
 	}
+	if mp, ok := container.(*objects.KulaMap); ok {
+		if !mp.Has(key) {
+			err := m.alloc(quotaObjectKeys, 1)
+			if err != nil {
+				return err
+			}
+		}
+		mp.Set(key, value)
+		return nil
+	}
 	return fmt.Errorf("cannot set key '%s' to container '%s'", key, container)
 }
 
-func (fn *VMFunction) calcVMFunction(argv []any) {
-	callStack.Push(CallInfo{
-		Ip:      ip,
-		Fp:      fp,
-		Context: context,
+func (m *Machine) calcVMFunction(fn *VMFunction, argv []any) error {
+	if m.maxCallDepth > 0 && m.callStack.Size() >= m.maxCallDepth {
+		return fmt.Errorf("%w: call depth over %d", ErrQuotaExceeded, m.maxCallDepth)
+	}
+	m.callStack.Push(CallInfo{
+		Ip:      m.ip,
+		Fp:      m.fp,
+		Context: m.context,
 	})
-	ip = -1
-	fp = fn.Index
-	context = NewContext(fn.Parent)
+	m.ip = -1
+	m.fp = fn.Index
+	m.context = NewContext(fn.Parent)
 
-	fc := CompiledFileInstance.Functions[fn.Index]
+	fc := m.file.Functions[fn.Index]
 	innerStack := utils.NewStack[any]()
-	currentStack = &innerStack
-	vmStack.Push(currentStack)
-	for i := 0; i < len(argv); i++ {
+	m.currentStack = &innerStack
+	m.vmStack.Push(m.currentStack)
+	for i := 0; i < len(argv) && i < len(fc.Params); i++ {
 		vIndex := fc.Params[i]
-		vName := CompiledFileInstance.SymbolArray[vIndex]
-		context.Define(vName, argv[i])
+		vName := m.file.SymbolArray[vIndex]
+		m.context.Define(vName, argv[i])
 	}
-	context.Define("self", fn)
+	m.context.Define("self", fn)
 	if fn.CallSite != nil {
-		context.Define("this", fn.CallSite)
+		m.context.Define("this", fn.CallSite)
 		fn.CallSite = nil
 	}
+	return nil
 }
 
 func (nf *NativeFunction) calcNativeFunction(argv []any) (val any, err error) {
+	if int(nf.Arity) > len(argv) {
+		return nil, fmt.Errorf("expect %d argument(s) but %d given", nf.Arity, len(argv))
+	}
 	return nf.Callee(nf.CallSite, argv)
 }
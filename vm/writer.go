@@ -0,0 +1,130 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"gokula/objects"
+	"io"
+	"math"
+)
+
+// WriteTo encodes the compiled file in the kulac format read by Load.
+func (kulac *CompiledFile) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+
+	binary.Write(&buf, binary.LittleEndian, MAGIC_NUMBER)
+
+	// Write Signal Table
+	for _, symbol := range kulac.SymbolArray {
+		if len(symbol) >= int(SEPRATOR) {
+			return 0, fmt.Errorf("symbol '%s' is too long", symbol)
+		}
+		buf.WriteByte(byte(len(symbol)))
+		buf.WriteString(symbol)
+	}
+	buf.WriteByte(SEPRATOR)
+
+	// Write Literals
+	if len(kulac.Literals) < literalBase {
+		return 0, fmt.Errorf("literal table is missing the implicit literals")
+	}
+	placeholders := kulac.placeholders
+	for i := literalBase; i <= len(kulac.Literals); i++ {
+		for len(placeholders) > 0 && placeholders[0].at <= i {
+			buf.WriteByte(placeholders[0].tag)
+			placeholders = placeholders[1:]
+		}
+		if i == len(kulac.Literals) {
+			break
+		}
+		switch literal := kulac.Literals[i].(type) {
+		case *objects.KulaString:
+			buf.WriteByte(STRING)
+			binary.Write(&buf, binary.LittleEndian, int32(len(*literal)))
+			buf.WriteString(string(*literal))
+		case objects.KulaNumber:
+			buf.WriteByte(DOUBLE)
+			binary.Write(&buf, binary.LittleEndian, literal)
+		default:
+			return 0, fmt.Errorf("cannot write literal %d of type '%s'", i, *TypeOf(literal))
+		}
+	}
+	buf.WriteByte(SEPRATOR)
+
+	// Write Instructions
+	err := writeInstructions(&buf, kulac.Chunk)
+	if err != nil {
+		return 0, err
+	}
+
+	// Write Functions
+	for fIndex, function := range kulac.Functions {
+		if len(function.Params) >= int(SEPRATOR) {
+			return 0, fmt.Errorf("function %d has too many params", fIndex)
+		}
+		buf.WriteByte(byte(len(function.Params)))
+		binary.Write(&buf, binary.LittleEndian, function.Params)
+		err = writeInstructions(&buf, function.Instructions)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// Write Debug
+	if kulac.Debug != nil {
+		err = writeDebug(&buf, kulac.Debug, len(kulac.Functions))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return buf.WriteTo(w)
+}
+
+func writeDebug(buf *bytes.Buffer, debug *DebugInfo, functions int) error {
+	if len(debug.Functions) > functions {
+		return fmt.Errorf("debug info has more blocks than functions")
+	}
+	buf.WriteByte(SEPRATOR)
+	binary.Write(buf, binary.LittleEndian, uint16(len(debug.Files)))
+	for _, name := range debug.Files {
+		if len(name) > math.MaxUint16 {
+			return fmt.Errorf("debug file name '%s' is too long", name)
+		}
+		binary.Write(buf, binary.LittleEndian, uint16(len(name)))
+		buf.WriteString(name)
+	}
+	for block := -1; block < functions; block++ {
+		entries := debug.block(block)
+		binary.Write(buf, binary.LittleEndian, uint32(len(entries)))
+		for _, entry := range entries {
+			binary.Write(buf, binary.LittleEndian, lineRecord{
+				uint32(entry.Index), uint16(entry.File), uint32(entry.Line), uint32(entry.Column),
+			})
+		}
+	}
+	return nil
+}
+
+func writeInstructions(buf *bytes.Buffer, instructions []Instruction) error {
+	for _, inst := range instructions {
+		if byte(inst.Op) == SEPRATOR {
+			return fmt.Errorf("illegal opcode 0x%x", byte(inst.Op))
+		}
+		if !fitsOperand(inst.Op, inst.Val) {
+			return fmt.Errorf("%s operand %d does not fit in %d bits", inst.Op, inst.Val, codeSize(inst.Op))
+		}
+		buf.WriteByte(byte(inst.Op))
+		switch codeSize(inst.Op) {
+		case 32:
+			binary.Write(buf, binary.LittleEndian, uint32(inst.Val))
+		case 16:
+			binary.Write(buf, binary.LittleEndian, uint16(inst.Val))
+		case 8:
+			buf.WriteByte(uint8(inst.Val))
+		}
+	}
+	buf.WriteByte(SEPRATOR)
+	return nil
+}